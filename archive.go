@@ -0,0 +1,232 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	pathpkg "path"
+	"path/filepath"
+	"strings"
+)
+
+// archiveMount makes the content of an archive readable as if the archive were
+// a directory at `path`.
+type archiveMount struct {
+	path    string
+	absPath string
+	fsys    fs.FS
+}
+
+// mounts contains the archives found in the fastlane paths and the metadata
+// directories read from `-git-ref`.
+var mounts []archiveMount
+
+// isArchive reports whether `path` names a supported archive format.
+func isArchive(path string) bool {
+	for _, ext := range []string{".zip", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(strings.ToLower(path), ext) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mountArchive mounts the archive that `path` points to or into, e.g.
+// `metadata.zip` or `artifact.tar.gz/fastlane/metadata/android`. It does
+// nothing if `path` doesn't contain an archive.
+func mountArchive(path string) error {
+	archivePath := ""
+	parts := strings.Split(filepath.ToSlash(filepath.Clean(path)), "/")
+	for i := range parts {
+		p := filepath.FromSlash(strings.Join(parts[:i+1], "/"))
+		if isArchive(p) {
+			if info, err := os.Stat(p); err == nil && info.Mode().IsRegular() {
+				archivePath = p
+				break
+			}
+		}
+	}
+
+	if archivePath == "" {
+		return nil
+	}
+
+	for _, m := range mounts {
+		if m.path == archivePath {
+			return nil
+		}
+	}
+
+	content, err := ioutil.ReadFile(archivePath)
+	if err != nil {
+		return err
+	}
+
+	if !strings.HasSuffix(strings.ToLower(archivePath), ".zip") {
+		if content, err = tarGzToZip(content); err != nil {
+			return err
+		}
+	}
+
+	return mountZip(archivePath, content)
+}
+
+// mountGitRef mounts the metadata directory at `path` as it is in the git
+// `ref`, reading it from the object database instead of the working tree. With
+// `-git-dir`, e.g. for a bare repository, `path` is relative to the root of the
+// repository. Otherwise, the repository is the one containing `path`, which
+// doesn't need to exist in the working tree.
+func mountGitRef(path, ref string) error {
+	if gitDir != "" {
+		if filepath.IsAbs(path) {
+			return fmt.Errorf("path must be relative to the repository root with -git-dir")
+		}
+
+		treePath := filepath.ToSlash(filepath.Clean(path))
+		if treePath == "." {
+			treePath = ""
+		}
+
+		content, err := runGit(".", "--git-dir="+gitDir, "archive", "--format=zip", ref+":"+treePath)
+		if err != nil {
+			return err
+		}
+
+		return mountZip(filepath.Clean(path), content)
+	}
+
+	// find the closest existing directory, since the metadata directory may
+	// only exist in `ref`.
+	dir, rest := filepath.Clean(path), make([]string, 0)
+	for {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			break
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+
+		rest = append([]string{filepath.Base(dir)}, rest...)
+		dir = parent
+	}
+
+	out, err := runGit(dir, "rev-parse", "--show-cdup", "--show-prefix")
+	if err != nil {
+		return fmt.Errorf("%w: use -git-dir to read a bare repository", err)
+	}
+
+	// git prints a line for each, even if empty, but only the cdup line outside
+	// of a working tree, e.g. in a bare repository.
+	lines := strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+	if len(lines) != 2 {
+		return fmt.Errorf("%q isn't in a git working tree: use -git-dir to read a bare repository", dir)
+	}
+
+	treePath := pathpkg.Join(lines[1], strings.Join(rest, "/"))
+	if treePath == "." {
+		treePath = ""
+	}
+
+	content, err := runGit(filepath.Join(dir, lines[0]), "archive", "--format=zip", ref+":"+treePath)
+	if err != nil {
+		return err
+	}
+
+	return mountZip(filepath.Clean(path), content)
+}
+
+// mountZip mounts the zip archive `content` at `path`.
+func mountZip(path string, content []byte) error {
+	r, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return err
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	mounts = append(mounts, archiveMount{path: path, absPath: abs, fsys: r})
+	return nil
+}
+
+// tarGzToZip repacks a gzipped tarball as an uncompressed zip archive, since
+// `archive/zip` already implements `fs.FS`.
+func tarGzToZip(content []byte) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	tr := tar.NewReader(gz)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		if h.Typeflag != tar.TypeReg && h.Typeflag != tar.TypeDir {
+			continue // links and special files can't be metadata
+		}
+
+		fh, err := zip.FileInfoHeader(h.FileInfo())
+		if err != nil {
+			return nil, err
+		}
+
+		fh.Name, fh.Method = pathpkg.Clean(h.Name), zip.Store
+		if fh.Name == "." {
+			continue
+		} else if h.Typeflag == tar.TypeDir {
+			fh.Name += "/"
+		}
+
+		w, err := zw.CreateHeader(fh)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := io.Copy(w, tr); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// mountedPath returns the file system of the archive containing `path`, which
+// may be absolute, and the name of `path` in it, or false if `path` isn't in an
+// archive.
+func mountedPath(path string) (fs.FS, string, bool) {
+	for _, m := range mounts {
+		root := m.path
+		if filepath.IsAbs(path) {
+			root = m.absPath
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return m.fsys, filepath.ToSlash(rel), true
+		}
+	}
+
+	return nil, "", false
+}
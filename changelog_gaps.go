@@ -0,0 +1,194 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// changelogGaps compares the changelog version codes of the locales in a
+// locale tree.
+type changelogGaps struct {
+	Path          string `json:"path"`
+	DefaultLocale string `json:"default_locale"`
+	// Latest is the highest version code of the default locale's changelogs.
+	Latest int `json:"latest"`
+	// Partial lists the version codes that only some locales have a changelog
+	// for.
+	Partial []int              `json:"partial"`
+	Locales []changelogGapsRow `json:"locales"`
+}
+
+type changelogGapsRow struct {
+	Locale         string `json:"locale"`
+	Latest         int    `json:"latest"`
+	MatchesDefault bool   `json:"matches_default"`
+	Missing        []int  `json:"missing"`
+}
+
+// changelogVersionCodes returns the sorted version codes of the changelogs in
+// `changelogsPath`.
+func changelogVersionCodes(changelogsPath string) []int {
+	files, err := readDir(changelogsPath)
+	if err != nil {
+		return nil
+	}
+
+	codes := make([]int, 0, len(files))
+	for _, file := range files {
+		code, err := strconv.Atoi(strings.TrimSuffix(file.Name(), ".txt"))
+		if err == nil && !file.IsDir() && changelogNameRegexp.MatchString(file.Name()) && !isSkipped(filepath.Join(changelogsPath, file.Name())) {
+			codes = append(codes, code)
+		}
+	}
+
+	sort.Ints(codes)
+	return codes
+}
+
+// buildChangelogGaps compares the changelog version codes of the locales in
+// `tree` against each other and against its default locale.
+func buildChangelogGaps(tree localeTree) changelogGaps {
+	g := changelogGaps{
+		Path:          tree.path,
+		DefaultLocale: defaultLocale,
+		Partial:       make([]int, 0),
+		Locales:       make([]changelogGapsRow, 0, len(tree.locales)),
+	}
+
+	codes := make(map[string][]int, len(tree.locales))
+	counts := make(map[int]int)
+	for _, locale := range tree.locales {
+		codes[locale] = changelogVersionCodes(layout.changelogsPath(filepath.Join(tree.path, locale)))
+		for _, code := range codes[locale] {
+			counts[code]++
+		}
+	}
+
+	for code, count := range counts {
+		if count < len(tree.locales) {
+			g.Partial = append(g.Partial, code)
+		}
+	}
+
+	sort.Ints(g.Partial)
+	latest := func(codes []int) int {
+		if len(codes) == 0 {
+			return 0
+		}
+
+		return codes[len(codes)-1]
+	}
+
+	g.Latest = latest(codes[defaultLocale])
+	for _, locale := range tree.locales {
+		row := changelogGapsRow{
+			Locale:         localeName(tree, locale),
+			Latest:         latest(codes[locale]),
+			MatchesDefault: latest(codes[locale]) == g.Latest,
+			Missing:        make([]int, 0),
+		}
+
+		for _, code := range g.Partial {
+			if i := sort.SearchInts(codes[locale], code); i == len(codes[locale]) || codes[locale][i] != code {
+				row.Missing = append(row.Missing, code)
+			}
+		}
+
+		g.Locales = append(g.Locales, row)
+	}
+
+	return g
+}
+
+// formatVersionCodes formats the sorted version codes as a comma separated
+// list, collapsing consecutive codes into ranges, e.g. "3-7, 10".
+func formatVersionCodes(codes []int) string {
+	if len(codes) == 0 {
+		return "-"
+	}
+
+	parts := make([]string, 0)
+	for start, end := 0, 1; end <= len(codes); end++ {
+		if end < len(codes) && codes[end] == codes[end-1]+1 {
+			continue
+		}
+
+		if end-1 == start {
+			parts = append(parts, strconv.Itoa(codes[start]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", codes[start], codes[end-1]))
+		}
+
+		start = end
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// changelogGapsCommand prints the latest changelog version code of each locale
+// and the version codes that only some locales have a changelog for, to make
+// missing translations of release notes visible.
+func changelogGapsCommand(args []string) {
+	fs := flag.NewFlagSet("changelog-gaps", flag.ExitOnError)
+	fs.Parse(args)
+
+	if defaultLocale == "" {
+		fmt.Fprintln(os.Stderr, "the changelog-gaps command requires -default-locale")
+		os.Exit(2)
+	}
+
+	if layout.releaseNotesDir != "" {
+		fmt.Fprintln(os.Stderr, "the changelog-gaps command is only supported by the fastlane layout: release notes aren't named after version codes")
+		os.Exit(2)
+	}
+
+	reports := make([]changelogGaps, 0)
+	for _, p := range fastlanePaths.paths {
+		trees, err := findLocaleTrees(p)
+		if err != nil {
+			const errFmt = "failed to read directory %q: %s\n"
+			fmt.Fprintf(os.Stderr, errFmt, p, err)
+			os.Exit(1)
+		}
+
+		for _, t := range trees {
+			reports = append(reports, buildChangelogGaps(t))
+		}
+	}
+
+	if outputFormat == "json" {
+		printJSON(reports)
+		return
+	}
+
+	for i, g := range reports {
+		if len(reports) > 1 {
+			if i > 0 {
+				fmt.Println()
+			}
+
+			fmt.Println(colorize(os.Stdout, colorBold, g.Path))
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "LOCALE\tLATEST\tMISSING")
+		for _, row := range g.Locales {
+			latest := colorize(os.Stdout, colorGreen, strconv.Itoa(row.Latest))
+			if row.Latest == 0 {
+				latest = colorize(os.Stdout, colorRed, "none")
+			} else if !row.MatchesDefault {
+				latest = colorize(os.Stdout, colorRed, strconv.Itoa(row.Latest))
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s\n", row.Locale, latest, formatVersionCodes(row.Missing))
+		}
+
+		w.Flush()
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// checkStaleChangelogs lists the changelogs that can be removed from the
+// locale: those older than the `staleChangelogsKeep` highest version codes
+// and, with `-stale-changelogs-days`, last committed more than that many days
+// ago. supply uploads every changelog on each run, so pruning them keeps the
+// uploads fast. It also warns if there are more than `maxChangelogs` files.
+func checkStaleChangelogs(changelogsPath string) []error {
+	if staleChangelogsKeep <= 0 && staleChangelogsDays <= 0 && maxChangelogs <= 0 {
+		return nil
+	}
+
+	files, err := readDir(changelogsPath)
+	if err != nil {
+		return nil // already reported by checkChangelogs
+	}
+
+	errs := make([]error, 0)
+	if maxChangelogs > 0 && len(files) > maxChangelogs {
+		const errFmt = "directory contains too many changelogs: expected<=%d, got=%d"
+		errs = append(errs, &validationError{
+			File:     changelogsPath,
+			Rule:     "changelog-count",
+			Err:      fmt.Errorf(errFmt, maxChangelogs, len(files)),
+			Severity: severityWarning,
+		})
+	}
+
+	if staleChangelogsKeep <= 0 && staleChangelogsDays <= 0 {
+		return errs
+	}
+
+	codes := make([]int, 0, len(files))
+	for _, file := range files {
+		code, err := strconv.Atoi(strings.TrimSuffix(file.Name(), ".txt"))
+		if err == nil && !file.IsDir() && changelogNameRegexp.MatchString(file.Name()) {
+			codes = append(codes, code)
+		}
+	}
+
+	sort.Sort(sort.Reverse(sort.IntSlice(codes)))
+	if staleChangelogsKeep > 0 {
+		if len(codes) <= staleChangelogsKeep {
+			return errs
+		}
+
+		codes = codes[staleChangelogsKeep:]
+	}
+
+	if staleChangelogsDays > 0 {
+		committed, err := changelogCommitTimes(changelogsPath)
+		if err != nil {
+			const errFmt = "failed to read the git history of %q: %w"
+			return append(errs, fmt.Errorf(errFmt, changelogsPath, err))
+		}
+
+		cutoff := time.Now().AddDate(0, 0, -staleChangelogsDays)
+		old := make([]int, 0, len(codes))
+		for _, code := range codes {
+			// changelogs without history are uncommitted and thus never stale
+			if t, ok := committed[fmt.Sprintf("%d.txt", code)]; ok && t.Before(cutoff) {
+				old = append(old, code)
+			}
+		}
+
+		codes = old
+	}
+
+	if len(codes) > 0 {
+		sort.Ints(codes)
+		names := make([]string, len(codes))
+		for i, code := range codes {
+			names[i] = fmt.Sprintf("%d.txt", code)
+		}
+
+		const errFmt = "%d changelogs are stale and can be removed: %s"
+		errs = append(errs, &validationError{
+			File:     changelogsPath,
+			Rule:     "stale-changelogs",
+			Err:      fmt.Errorf(errFmt, len(codes), strings.Join(names, ", ")),
+			Severity: severityWarning,
+		})
+	}
+
+	return errs
+}
+
+// changelogCommitTimes returns the time of the last commit that changed each
+// file in `changelogsPath`, keyed by file name. The metadata read from
+// `-git-ref` has the history of the ref, and archives have none.
+func changelogCommitTimes(changelogsPath string) (map[string]time.Time, error) {
+	args := []string{"log", "--format=%x00%ct", "--name-only"}
+	dir, pathspec := changelogsPath, "."
+	if _, _, ok := mountedPath(changelogsPath); ok {
+		if gitRef == "" {
+			return nil, nil
+		}
+
+		dir, pathspec, args = ".", changelogsPath, append(args, gitRef)
+	}
+
+	out, err := runGit(dir, append(args, "--", pathspec)...)
+	if err != nil {
+		return nil, err
+	}
+
+	times := make(map[string]time.Time)
+	for _, entry := range bytes.Split(out, []byte{0}) {
+		lines := strings.Split(strings.TrimSpace(string(entry)), "\n")
+		secs, err := strconv.ParseInt(lines[0], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		for _, name := range lines[1:] {
+			name = filepath.Base(strings.TrimSpace(name))
+			if _, ok := times[name]; !ok && name != "." {
+				times[name] = time.Unix(secs, 0) // git log lists the newest first
+			}
+		}
+	}
+
+	return times, nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import "os"
+
+const (
+	colorReset  = "\x1b[0m"
+	colorBold   = "\x1b[1m"
+	colorRed    = "\x1b[31m"
+	colorGreen  = "\x1b[32m"
+	colorYellow = "\x1b[33m"
+)
+
+// useColor reports whether the output written to `f` should be colorized. In
+// the `auto` mode, it respects the `NO_COLOR` and `FORCE_COLOR` environment
+// variables before falling back to check if `f` is a terminal.
+func useColor(f *os.File) bool {
+	switch colorMode {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+
+	if v := os.Getenv("FORCE_COLOR"); v != "" && v != "0" {
+		return true
+	}
+
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// colorize wraps `s` in the given ANSI color if the output written to `f`
+// should be colorized.
+func colorize(f *os.File, color, s string) string {
+	if !useColor(f) {
+		return s
+	}
+
+	return color + s + colorReset
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+// comparison lists the issues that differ between two reports.
+type comparison struct {
+	New        []reportIssue `json:"new"`
+	Fixed      []reportIssue `json:"fixed"`
+	Persisting []reportIssue `json:"persisting"`
+}
+
+// compareCommand prints new, fixed and persisting issues between two JSON
+// reports.
+func compareCommand(args []string) {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: compare old-report.json new-report.json")
+	}
+
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	reports := make([]*report, 2)
+	for i, path := range fs.Args() {
+		r, err := readReport(path)
+		if err != nil {
+			const errFmt = "failed to read report %q: %s\n"
+			fmt.Fprintf(os.Stderr, errFmt, path, err)
+			os.Exit(1)
+		}
+
+		reports[i] = r
+	}
+
+	c := compareReports(reports[0], reports[1])
+	if outputFormat == "json" {
+		printJSON(c)
+		return
+	}
+
+	printIssues := func(title string, issues []reportIssue) {
+		fmt.Printf("%s (%d):\n", title, len(issues))
+		for _, i := range issues {
+			fmt.Printf("  %s\n", i)
+		}
+	}
+
+	printIssues("new issues", c.New)
+	printIssues("fixed issues", c.Fixed)
+	printIssues("persisting issues", c.Persisting)
+}
+
+// compareReports returns the issues present only in `newReport`, only in
+// `oldReport` and in both.
+func compareReports(oldReport, newReport *report) *comparison {
+	c := &comparison{
+		New:        make([]reportIssue, 0),
+		Fixed:      make([]reportIssue, 0),
+		Persisting: make([]reportIssue, 0),
+	}
+
+	old := make(map[reportIssue]int)
+	for _, i := range oldReport.Issues {
+		old[i]++
+	}
+
+	for _, i := range newReport.Issues {
+		if old[i] > 0 {
+			old[i]--
+			c.Persisting = append(c.Persisting, i)
+		} else {
+			c.New = append(c.New, i)
+		}
+	}
+
+	for _, i := range oldReport.Issues {
+		if old[i] > 0 {
+			old[i]--
+			c.Fixed = append(c.Fixed, i)
+		}
+	}
+
+	return c
+}
+
+func (i reportIssue) String() string {
+	if i.File == "" {
+		return fmt.Sprintf("%s: %s", i.Severity, i.Message)
+	}
+
+	return fmt.Sprintf("%s: %s: %s", i.File, i.Severity, i.Message)
+}
@@ -0,0 +1,349 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+)
+
+// descriptiveFiles are the text files that make up a complete listing.
+var descriptiveFiles = []string{"title.txt", "short_description.txt", "full_description.txt"}
+
+// isCompleteLocale reports whether the locale has a non-empty title, short
+// description and full description.
+func isCompleteLocale(localePath string) bool {
+	for _, file := range descriptiveFiles {
+		count, err := getCharacterCount(layout.textPath(localePath, file))
+		if err != nil || count == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// checkMinLocales checks that there are at least `minLocales` complete locales
+// in the locale tree.
+func checkMinLocales(tree localeTree) []error {
+	complete := 0
+	for _, l := range tree.locales {
+		if isCompleteLocale(filepath.Join(tree.path, l)) {
+			complete++
+		}
+	}
+
+	if complete < minLocales {
+		const errFmt = "expected at least %d complete locales: got=%d"
+		return []error{&validationError{
+			File: tree.path,
+			Rule: "min-locales",
+			Err:  fmt.Errorf(errFmt, minLocales, complete),
+		}}
+	}
+
+	return nil
+}
+
+// checkDefaultLocale checks that the locale tree has a complete default
+// locale, since supply can't upload the listing without it.
+func checkDefaultLocale(tree localeTree) []error {
+	if defaultLocale == "" {
+		return nil
+	}
+
+	localePath := filepath.Join(tree.path, defaultLocale)
+	if !containsString(tree.locales, defaultLocale) {
+		const errFmt = "default locale %q is missing"
+		return []error{&validationError{
+			File: tree.path,
+			Rule: "default-locale",
+			Err:  fmt.Errorf(errFmt, defaultLocale),
+		}}
+	}
+
+	if !isCompleteLocale(localePath) {
+		const errFmt = "default locale %q is incomplete: expected a non-empty title, short description and full description"
+		return []error{&validationError{
+			File: localePath,
+			Rule: "default-locale",
+			Err:  fmt.Errorf(errFmt, defaultLocale),
+		}}
+	}
+
+	return nil
+}
+
+// checkUntranslated warns if the descriptions of the locale at `localePath`
+// are byte-identical to those of the default locale, unless the locale matches
+// an `-allow-untranslated` pattern. An identical title alone is normal for
+// brand names, so it's only listed along with an identical description.
+func checkUntranslated(localePath string) []error {
+	locale := filepath.Base(localePath)
+	if defaultLocale == "" || locale == defaultLocale || matchesAnyGlob(allowUntranslated.paths, locale) {
+		return nil
+	}
+
+	defaultPath := filepath.Join(filepath.Dir(localePath), defaultLocale)
+	identical := make([]string, 0)
+	for _, file := range descriptiveFiles {
+		content, err := readFile(layout.textPath(localePath, file))
+		if err != nil || len(bytes.TrimSpace(content)) == 0 {
+			continue // already reported by checkDescriptiveTexts
+		}
+
+		defaultContent, err := readFile(layout.textPath(defaultPath, file))
+		if err == nil && bytes.Equal(content, defaultContent) {
+			identical = append(identical, file)
+		}
+	}
+
+	if len(identical) == 0 || len(identical) == 1 && identical[0] == "title.txt" {
+		return nil
+	}
+
+	const errFmt = "seems untranslated: %s identical to the default locale %q"
+	return []error{&validationError{
+		File:     localePath,
+		Rule:     "untranslated",
+		Err:      fmt.Errorf(errFmt, strings.Join(identical, ", "), defaultLocale),
+		Severity: severityWarning,
+	}}
+}
+
+// defaultChangelogCodes contains the changelog version codes of the default
+// locale by the path of its locale tree, so that checkChangelogTranslations
+// reads them once per tree.
+var defaultChangelogCodes map[string][]int
+
+// loadDefaultChangelogCodes returns the changelog version codes of the default
+// locale of each of the locale `trees` by the path of the tree.
+func loadDefaultChangelogCodes(trees []localeTree) map[string][]int {
+	codes := make(map[string][]int, len(trees))
+	if defaultLocale == "" {
+		return codes
+	}
+
+	for _, t := range trees {
+		defaultPath := filepath.Join(t.path, defaultLocale)
+		codes[filepath.Clean(t.path)] = changelogVersionCodes(layout.changelogsPath(defaultPath))
+	}
+
+	return codes
+}
+
+// checkChangelogTranslations warns about each changelog of the default locale
+// that the locale at `localePath` is missing, naming the missing file, so that
+// translators get a precise work list. Locales with a `default.txt`, which
+// supply uploads instead, and those matching an `-allow-untranslated` pattern
+// are left out.
+func checkChangelogTranslations(localePath string) []error {
+	locale := filepath.Base(localePath)
+	if defaultLocale == "" || locale == defaultLocale || matchesAnyGlob(allowUntranslated.paths, locale) {
+		return nil
+	}
+
+	changelogsPath := layout.changelogsPath(localePath)
+	if _, err := statFile(filepath.Join(changelogsPath, "default.txt")); err == nil {
+		return nil
+	}
+
+	translated := make(map[int]bool)
+	for _, code := range changelogVersionCodes(changelogsPath) {
+		translated[code] = true
+	}
+
+	errs := make([]error, 0)
+	for _, code := range defaultChangelogCodes[filepath.Dir(localePath)] {
+		if translated[code] {
+			continue
+		}
+
+		const errFmt = "changelog is missing: the default locale %q has one for version code %d"
+		errs = append(errs, &validationError{
+			File:     filepath.Join(changelogsPath, fmt.Sprintf("%d.txt", code)),
+			Rule:     "changelog-translation",
+			Err:      fmt.Errorf(errFmt, defaultLocale, code),
+			Severity: severityWarning,
+		})
+	}
+
+	return errs
+}
+
+// completenessItem is a piece of metadata that the default locale has and the
+// other locales are compared against.
+type completenessItem struct {
+	name    string
+	present func(localePath string) bool
+}
+
+// completenessMatrix lists the items of the default locale that each locale in
+// a locale tree is missing.
+type completenessMatrix struct {
+	Path          string            `json:"path"`
+	DefaultLocale string            `json:"default_locale"`
+	Items         []string          `json:"items"`
+	Locales       []completenessRow `json:"locales"`
+}
+
+type completenessRow struct {
+	Locale  string   `json:"locale"`
+	Missing []string `json:"missing"`
+}
+
+// completenessItems returns the items of the default locale at
+// `defaultLocalePath`: the descriptive texts, the changelog of the release,
+// the images and the screenshot types.
+func completenessItems(defaultLocalePath string) []completenessItem {
+	items := make([]completenessItem, 0)
+	hasText := func(name string) func(string) bool {
+		return func(localePath string) bool {
+			count, err := getCharacterCount(layout.textPath(localePath, name))
+			return err == nil && count > 0
+		}
+	}
+
+	for _, name := range append(descriptiveFiles, "video.txt") {
+		if hasText(name)(defaultLocalePath) {
+			items = append(items, completenessItem{name: name, present: hasText(name)})
+		}
+	}
+
+	if layout.releaseNotesDir == "" {
+		changelog := latestChangelog(layout.changelogsPath(defaultLocalePath))
+		if versionCode > 0 {
+			changelog = fmt.Sprintf("%d.txt", versionCode)
+		}
+
+		if changelog != "" {
+			name := filepath.Join("changelogs", filepath.Base(changelog))
+			items = append(items, completenessItem{name: filepath.ToSlash(name), present: func(localePath string) bool {
+				_, err := getCharacterCount(filepath.Join(localePath, name))
+				return err == nil
+			}})
+		}
+	}
+
+	imagesPath := func(localePath string) string { return filepath.Join(localePath, layout.imagesDir) }
+	for _, name := range imageNames {
+		name := name
+		if layout.hasImage(imagesPath(defaultLocalePath), name) {
+			items = append(items, completenessItem{name: name, present: func(localePath string) bool {
+				return layout.hasImage(imagesPath(localePath), name)
+			}})
+		}
+	}
+
+	hasScreenshots := func(name string) func(string) bool {
+		return func(localePath string) bool {
+			files, _ := readDir(layout.imagePath(imagesPath(localePath), name))
+			for _, f := range files {
+				if !f.IsDir() {
+					return true
+				}
+			}
+
+			return false
+		}
+	}
+
+	for _, name := range screenshotDirs {
+		if hasScreenshots(name)(defaultLocalePath) {
+			items = append(items, completenessItem{name: name, present: hasScreenshots(name)})
+		}
+	}
+
+	return items
+}
+
+// buildCompletenessMatrix compares the locales in `tree` against its default
+// locale.
+func buildCompletenessMatrix(tree localeTree) completenessMatrix {
+	m := completenessMatrix{
+		Path:          tree.path,
+		DefaultLocale: defaultLocale,
+		Items:         make([]string, 0),
+		Locales:       make([]completenessRow, 0, len(tree.locales)),
+	}
+
+	items := completenessItems(filepath.Join(tree.path, defaultLocale))
+	for _, item := range items {
+		m.Items = append(m.Items, item.name)
+	}
+
+	for _, locale := range tree.locales {
+		row := completenessRow{Locale: localeName(tree, locale), Missing: make([]string, 0)}
+		for _, item := range items {
+			if !item.present(filepath.Join(tree.path, locale)) {
+				row.Missing = append(row.Missing, item.name)
+			}
+		}
+
+		m.Locales = append(m.Locales, row)
+	}
+
+	return m
+}
+
+// matrixCommand prints which of the texts, the release changelog and the
+// graphics of the default locale each locale is missing, to make translation
+// gaps visible.
+func matrixCommand(args []string) {
+	fs := flag.NewFlagSet("matrix", flag.ExitOnError)
+	fs.Parse(args)
+
+	if defaultLocale == "" {
+		fmt.Fprintln(os.Stderr, "the matrix command requires -default-locale")
+		os.Exit(2)
+	}
+
+	matrices := make([]completenessMatrix, 0)
+	for _, p := range fastlanePaths.paths {
+		trees, err := findLocaleTrees(p)
+		if err != nil {
+			const errFmt = "failed to read directory %q: %s\n"
+			fmt.Fprintf(os.Stderr, errFmt, p, err)
+			os.Exit(1)
+		}
+
+		for _, t := range trees {
+			matrices = append(matrices, buildCompletenessMatrix(t))
+		}
+	}
+
+	if outputFormat == "json" {
+		printJSON(matrices)
+		return
+	}
+
+	for i, m := range matrices {
+		if len(matrices) > 1 {
+			if i > 0 {
+				fmt.Println()
+			}
+
+			fmt.Println(colorize(os.Stdout, colorBold, m.Path))
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "LOCALE\t"+strings.Join(m.Items, "\t"))
+		for _, row := range m.Locales {
+			cells := []string{row.Locale}
+			for _, item := range m.Items {
+				if containsString(row.Missing, item) {
+					cells = append(cells, colorize(os.Stdout, colorRed, "missing"))
+				} else {
+					cells = append(cells, colorize(os.Stdout, colorGreen, "ok"))
+				}
+			}
+
+			fmt.Fprintln(w, strings.Join(cells, "\t"))
+		}
+
+		w.Flush()
+	}
+}
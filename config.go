@@ -0,0 +1,465 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// screenshotSpec declares the constraints for screenshots in a `*Screenshots`
+// directory. 0 disables the `MaxEdge`, the `MaxAspectRatio`, the `MaxBytes` and
+// the `MaxCount` checks.
+type screenshotSpec struct {
+	MinEdge        int     `json:"minEdge"`
+	MaxEdge        int     `json:"maxEdge"`
+	MaxAspectRatio float64 `json:"maxAspectRatio"`
+	MaxBytes       int64   `json:"maxBytes"`
+	MaxCount       int     `json:"maxCount"`
+
+	// AspectRatios are the only width:height ratios allowed, e.g. "9:16" for
+	// portrait screenshots, if any.
+	AspectRatios []string `json:"aspectRatios"`
+	// Resolutions are the only resolutions allowed, e.g. "1920x1080", if any.
+	Resolutions []string `json:"resolutions"`
+
+	// RecommendedMinShortEdge is the shortest edge in pixels below which the
+	// screenshots don't qualify for featuring on Play. 0 disables the warning.
+	RecommendedMinShortEdge int `json:"recommendedMinShortEdge"`
+}
+
+// textLimitRule overrides the maximum and the minimum lengths of descriptive
+// text files. A rule only applies to the `Locales` (glob patterns) and
+// `Targets` it lists, or to all if it doesn't list any.
+type textLimitRule struct {
+	Targets    []string       `json:"targets"`
+	Locales    []string       `json:"locales"`
+	Files      map[string]int `json:"files"`
+	MinLengths map[string]int `json:"minLengths"`
+}
+
+// textFileSpec declares an additional text file to validate in every locale.
+type textFileSpec struct {
+	Name      string   `json:"name"`
+	MaxLength int      `json:"maxLength"`
+	Required  bool     `json:"required"`
+	Rules     []string `json:"rules"`
+}
+
+// textFileRules maps the content rules that can be enabled for additional
+// text files to their checks.
+var textFileRules = map[string]func(filePath string) []error{
+	"plain-text":    checkPlainText,
+	"repeated-word": checkRepeatedWordsInFile,
+}
+
+// requiredAssetsRule declares the graphics that are mandatory in the `Locales`
+// (glob patterns) it lists, or in all locales if it doesn't list any. If
+// `DefaultLocale` is set, it only applies to the default locale.
+type requiredAssetsRule struct {
+	Locales       []string       `json:"locales"`
+	DefaultLocale bool           `json:"defaultLocale"`
+	Images        []string       `json:"images"`
+	Screenshots   map[string]int `json:"screenshots"`
+}
+
+// wordListRule declares a list of terms, e.g. banned words, that applies to
+// the `Locales` (glob patterns) it lists, or to all locales if it doesn't list
+// any. `File` is the path of a dictionary with a term per line, relative to the
+// config file.
+type wordListRule struct {
+	Locales []string `json:"locales"`
+	Words   []string `json:"words"`
+	File    string   `json:"file"`
+
+	regexps []*regexp.Regexp
+}
+
+// profanityConfig declares the opt-in screen of all public-facing texts for
+// offensive language. The `Lists` that apply to a locale are combined.
+type profanityConfig struct {
+	Lists    []wordListRule `json:"lists"`
+	Severity string         `json:"severity"`
+
+	severity severity
+}
+
+// config declares the options that can be specified in the config file.
+type config struct {
+	Screenshots        map[string]screenshotSpec `json:"screenshots"`
+	TextLimits         []textLimitRule           `json:"textLimits"`
+	TextFiles          []textFileSpec            `json:"textFiles"`
+	RequiredAssets     []requiredAssetsRule      `json:"requiredAssets"`
+	MinLocales         int                       `json:"minLocales"`
+	Placeholders       []string                  `json:"placeholders"`
+	BannedWords        []wordListRule            `json:"bannedWords"`
+	Spellcheck         *spellcheckConfig         `json:"spellcheck"`
+	Profanity          *profanityConfig          `json:"profanity"`
+	ChangelogMaxLength int                       `json:"changelogMaxLength"`
+	ImageMaxBytes      map[string]int64          `json:"imageMaxBytes"`
+
+	placeholderRegexps []*regexp.Regexp
+}
+
+// defaultScreenshotSpec applies to all screenshot types that don't have an
+// explicit spec in the Play profile.
+var defaultScreenshotSpec = screenshotSpec{
+	MinEdge:        320,
+	MaxEdge:        3840,
+	MaxAspectRatio: 2.3,
+	MaxBytes:       8 << 20,
+	MaxCount:       8,
+}
+
+// phoneScreenshotSpec applies to the phone screenshots in the Play profile,
+// which qualify for featuring from 1080px on the short edge.
+var phoneScreenshotSpec = screenshotSpec{
+	MinEdge:                 320,
+	MaxEdge:                 3840,
+	MaxAspectRatio:          2.3,
+	MaxBytes:                8 << 20,
+	MaxCount:                8,
+	RecommendedMinShortEdge: 1080,
+}
+
+// tabletScreenshotSpec applies to the tablet screenshots in the Play profile,
+// which may be up to 7680px large.
+var tabletScreenshotSpec = screenshotSpec{
+	MinEdge:                 320,
+	MaxEdge:                 7680,
+	MaxAspectRatio:          2.3,
+	MaxBytes:                8 << 20,
+	MaxCount:                8,
+	RecommendedMinShortEdge: 1080,
+}
+
+// wearScreenshotSpec applies to the Wear OS screenshots in the Play profile,
+// which must be square.
+var wearScreenshotSpec = screenshotSpec{
+	MinEdge:      384,
+	MaxEdge:      3840,
+	AspectRatios: []string{"1:1"},
+	MaxBytes:     8 << 20,
+	MaxCount:     8,
+}
+
+// tvScreenshotSpec applies to the Android TV screenshots in the Play profile,
+// which must be landscape 16:9 screenshots in one of the TV resolutions. Play
+// recommends at least 1920x1080.
+var tvScreenshotSpec = screenshotSpec{
+	MinEdge:                 720,
+	MaxEdge:                 3840,
+	Resolutions:             []string{"1280x720", "1920x1080", "3840x2160"},
+	MaxBytes:                8 << 20,
+	MaxCount:                8,
+	RecommendedMinShortEdge: 1080,
+}
+
+// defaultPlaceholders match the common template markers and filler texts that
+// mustn't end up on the store listing.
+var defaultPlaceholders = []string{
+	`(?i)\blorem ipsum\b`,
+	`\b(TODO|FIXME|TBD|CHANGEME|CHANGE ME)\b`,
+	`(?i)\b(description|text|title|changelog) goes here\b`,
+	`(?i)\binsert (a |the |your )?(description|text|title|changelog)\b`,
+}
+
+// cfg is the config in use. It is replaced by the config file if one is
+// specified.
+var cfg = defaultConfig()
+
+// defaultConfig returns the config with the defaults of the active profile.
+func defaultConfig() *config {
+	c := &config{Screenshots: make(map[string]screenshotSpec)}
+	for name, spec := range activeProfile.screenshots {
+		c.Screenshots[name] = spec
+	}
+
+	for _, p := range defaultPlaceholders {
+		c.placeholderRegexps = append(c.placeholderRegexps, regexp.MustCompile(p))
+	}
+
+	return c
+}
+
+// loadConfig reads the JSON config file at `path`. The options that the file
+// doesn't specify retain their default values.
+func loadConfig(path string) (*config, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw struct {
+		Screenshots        map[string]json.RawMessage `json:"screenshots"`
+		TextLimits         []textLimitRule            `json:"textLimits"`
+		TextFiles          []textFileSpec             `json:"textFiles"`
+		RequiredAssets     []requiredAssetsRule       `json:"requiredAssets"`
+		MinLocales         int                        `json:"minLocales"`
+		Placeholders       []string                   `json:"placeholders"`
+		BannedWords        []wordListRule             `json:"bannedWords"`
+		Spellcheck         *spellcheckConfig          `json:"spellcheck"`
+		Profanity          *profanityConfig           `json:"profanity"`
+		ChangelogMaxLength int                        `json:"changelogMaxLength"`
+		ImageMaxBytes      map[string]int64           `json:"imageMaxBytes"`
+	}
+
+	if err := json.Unmarshal(content, &raw); err != nil {
+		return nil, err
+	}
+
+	c := defaultConfig()
+	c.TextLimits = raw.TextLimits
+	c.TextFiles = raw.TextFiles
+	c.RequiredAssets = raw.RequiredAssets
+	c.MinLocales = raw.MinLocales
+	c.ChangelogMaxLength = raw.ChangelogMaxLength
+	c.ImageMaxBytes = raw.ImageMaxBytes
+	c.Placeholders = raw.Placeholders
+	for i, p := range c.Placeholders {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("placeholders[%d]: %w", i, err)
+		}
+
+		c.placeholderRegexps = append(c.placeholderRegexps, re)
+	}
+
+	c.BannedWords = raw.BannedWords
+	for i := range c.BannedWords {
+		if err := c.BannedWords[i].compile(filepath.Dir(path)); err != nil {
+			return nil, fmt.Errorf("bannedWords[%d]: %w", i, err)
+		}
+	}
+
+	c.Profanity = raw.Profanity
+	if c.Profanity != nil {
+		if c.Profanity.severity, err = parseSeverity(c.Profanity.Severity); err != nil {
+			return nil, fmt.Errorf("profanity.severity: %w", err)
+		}
+
+		for i := range c.Profanity.Lists {
+			if err := c.Profanity.Lists[i].compile(filepath.Dir(path)); err != nil {
+				return nil, fmt.Errorf("profanity.lists[%d]: %w", i, err)
+			}
+		}
+	}
+
+	c.Spellcheck = raw.Spellcheck
+	if c.Spellcheck != nil {
+		if err := c.Spellcheck.compile(filepath.Dir(path)); err != nil {
+			return nil, fmt.Errorf("spellcheck.%w", err)
+		}
+	}
+
+	for i, spec := range c.TextFiles {
+		if spec.Name == "" {
+			return nil, fmt.Errorf("textFiles[%d].name: must not be empty", i)
+		}
+
+		for _, rule := range spec.Rules {
+			if _, ok := textFileRules[rule]; !ok {
+				return nil, fmt.Errorf("textFiles[%d].rules: unknown rule %q", i, rule)
+			}
+		}
+	}
+	for name, specJSON := range raw.Screenshots {
+		spec := c.screenshotSpec(name)
+		if err := json.Unmarshal(specJSON, &spec); err != nil {
+			return nil, fmt.Errorf("screenshots.%s: %w", name, err)
+		}
+
+		for i, ratio := range spec.AspectRatios {
+			if _, err := parseAspectRatio(ratio); err != nil {
+				return nil, fmt.Errorf("screenshots.%s.aspectRatios[%d]: %w", name, i, err)
+			}
+		}
+
+		c.Screenshots[name] = spec
+	}
+
+	return c, nil
+}
+
+// compile reads the dictionary file of the rule, if any, and compiles its terms
+// into case-insensitive regular expressions matching whole words.
+func (r *wordListRule) compile(configDir string) error {
+	words := append([]string{}, r.Words...)
+	if r.File != "" {
+		filePath := r.File
+		if !filepath.IsAbs(filePath) {
+			filePath = filepath.Join(configDir, filePath)
+		}
+
+		content, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+
+		for _, line := range strings.Split(string(content), "\n") {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+				words = append(words, line)
+			}
+		}
+	}
+
+	for _, w := range words {
+		const boundary = `[^\p{L}\p{N}]`
+		re := regexp.MustCompile(`(?i)(?:^|` + boundary + `)(` + regexp.QuoteMeta(w) + `)(?:$|` + boundary + `)`)
+		r.regexps = append(r.regexps, re)
+	}
+
+	return nil
+}
+
+// parseSeverity returns the severity named `name` in the config, defaulting to
+// a warning if it is empty.
+func parseSeverity(name string) (severity, error) {
+	switch name {
+	case "", "warning":
+		return severityWarning, nil
+	case "error":
+		return severityError, nil
+	}
+
+	return severityWarning, fmt.Errorf("expected warning or error, got %q", name)
+}
+
+// wordListRegexps returns the regular expressions matching the terms of the
+// `rules` that apply to the given locale.
+func wordListRegexps(rules []wordListRule, locale string) []*regexp.Regexp {
+	regexps := make([]*regexp.Regexp, 0)
+	for _, rule := range rules {
+		if len(rule.Locales) == 0 || matchesAnyGlob(rule.Locales, locale) {
+			regexps = append(regexps, rule.regexps...)
+		}
+	}
+
+	return regexps
+}
+
+// textLimits returns the maximum lengths of descriptive text files for the
+// given locale. The matching rules apply in order, so that later rules override
+// earlier ones, and `-max-length` overrides them all.
+func (c *config) textLimits(locale string) map[string]int {
+	limits := make(map[string]int)
+	for file, limit := range activeProfile.textLimits {
+		limits[file] = limit
+	}
+
+	for _, rule := range c.matchingTextLimits(locale) {
+		for file, limit := range rule.Files {
+			limits[file] = limit
+		}
+	}
+
+	for file, limit := range maxLengths {
+		if file != "changelogs" {
+			limits[file] = limit
+		}
+	}
+
+	return limits
+}
+
+// changelogMaxLength returns the maximum length of changelogs, overridden by
+// `-max-length changelogs=<n>` and the config in that order.
+func (c *config) changelogMaxLength() int {
+	if limit, ok := maxLengths["changelogs"]; ok {
+		return limit
+	}
+
+	if c.ChangelogMaxLength > 0 {
+		return c.ChangelogMaxLength
+	}
+
+	return activeProfile.changelogMaxLength
+}
+
+// imageMaxBytes returns the maximum file size in bytes of the image with the
+// fastlane `name`, e.g. `icon`, or 0 if it isn't limited.
+func (c *config) imageMaxBytes(name string) int64 {
+	if limit, ok := c.ImageMaxBytes[name]; ok {
+		return limit
+	}
+
+	return activeProfile.imageMaxBytes[name]
+}
+
+// textMinLengths returns the minimum lengths of descriptive text files for the
+// given locale, like `textLimits`.
+func (c *config) textMinLengths(locale string) map[string]int {
+	minLengths := make(map[string]int)
+	for file, length := range activeProfile.textMinLengths {
+		minLengths[file] = length
+	}
+
+	for _, rule := range c.matchingTextLimits(locale) {
+		for file, length := range rule.MinLengths {
+			minLengths[file] = length
+		}
+	}
+
+	return minLengths
+}
+
+// matchingTextLimits returns the text limit rules that apply to the given
+// locale and the `-target`.
+func (c *config) matchingTextLimits(locale string) []textLimitRule {
+	rules := make([]textLimitRule, 0, len(c.TextLimits))
+	for _, rule := range c.TextLimits {
+		if len(rule.Targets) > 0 && !containsString(rule.Targets, target) {
+			continue
+		}
+
+		if len(rule.Locales) > 0 && !matchesAnyGlob(rule.Locales, locale) {
+			continue
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules
+}
+
+// parseAspectRatio parses a width:height ratio, e.g. "16:9".
+func parseAspectRatio(ratio string) (float64, error) {
+	parts := strings.SplitN(ratio, ":", 2)
+	if len(parts) == 2 {
+		w, errW := strconv.ParseFloat(parts[0], 64)
+		h, errH := strconv.ParseFloat(parts[1], 64)
+		if errW == nil && errH == nil && w > 0 && h > 0 {
+			return w / h, nil
+		}
+	}
+
+	return 0, fmt.Errorf("expected a width:height ratio, e.g. 16:9, got %q", ratio)
+}
+
+// matchesAnyGlob reports whether `name` matches any of the glob `patterns`.
+func matchesAnyGlob(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// screenshotSpec returns the spec for the given screenshot directory name.
+func (c *config) screenshotSpec(name string) screenshotSpec {
+	if spec, ok := c.Screenshots[name]; ok {
+		return spec
+	}
+
+	if activeProfile.strictGraphics {
+		return defaultScreenshotSpec
+	}
+
+	return screenshotSpec{}
+}
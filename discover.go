@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// discoverSkippedDirs contains the names of directories that aren't searched
+// for fastlane metadata, e.g. dependencies and build outputs.
+var discoverSkippedDirs = map[string]bool{
+	".git":         true,
+	".gradle":      true,
+	"build":        true,
+	"node_modules": true,
+}
+
+// discoverFastlanePaths returns all `fastlane/metadata/android` directories in
+// `rootPath`, e.g. the metadata of every app in a monorepo.
+func discoverFastlanePaths(rootPath string) ([]string, error) {
+	paths := make([]string, 0)
+	err := filepath.Walk(rootPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			return err
+		}
+
+		if path != rootPath && discoverSkippedDirs[info.Name()] {
+			return filepath.SkipDir
+		}
+
+		metadataPath := filepath.Dir(path)
+		if info.Name() == "android" && filepath.Base(metadataPath) == "metadata" &&
+			filepath.Base(filepath.Dir(metadataPath)) == "fastlane" {
+			paths = append(paths, path)
+			return filepath.SkipDir
+		}
+
+		return nil
+	})
+
+	return paths, err
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// exifOrientationNames describe the EXIF orientations other than the normal
+// one (1).
+var exifOrientationNames = map[int]string{
+	2: "mirrored horizontally",
+	3: "rotated 180°",
+	4: "mirrored vertically",
+	5: "mirrored and rotated 90° counterclockwise",
+	6: "rotated 90° clockwise",
+	7: "mirrored and rotated 90° clockwise",
+	8: "rotated 90° counterclockwise",
+}
+
+// exifOrientation returns the EXIF orientation (1-8) of the JPEG or PNG image
+// from its parsed segments or chunks, or 1 if it doesn't have one.
+func exifOrientation(config *imageConfig) int {
+	var exif []byte
+	switch config.format {
+	case "jpeg":
+		for _, s := range config.segments {
+			if s.marker == 0xE1 && bytes.HasPrefix(s.data, []byte("Exif\x00\x00")) {
+				exif = s.data[6:]
+				break
+			}
+		}
+	case "png":
+		for _, c := range config.chunks {
+			if c.kind == "eXIf" {
+				exif = c.data
+				break
+			}
+		}
+	}
+
+	if o := tiffOrientation(exif); o >= 1 && o <= 8 {
+		return o
+	}
+
+	return 1
+}
+
+// tiffOrientation returns the value of the Orientation tag (0x0112) in the
+// first IFD of the TIFF structure that EXIF data uses, or 0 if it has none.
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 0
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 0
+	}
+
+	count := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < count; i++ {
+		entry := ifd + 2 + 12*i
+		if entry+12 > len(tiff) {
+			break
+		}
+
+		// tag, type (3 = SHORT), count and the value itself
+		if order.Uint16(tiff[entry:]) == 0x0112 && order.Uint16(tiff[entry+2:]) == 3 {
+			return int(order.Uint16(tiff[entry+8:]))
+		}
+	}
+
+	return 0
+}
+
+// checkEXIFOrientation warns if the image has an EXIF orientation other than
+// the normal one. Its dimensions are validated as displayed, i.e. rotated, but
+// not every consumer of the image honours the tag.
+func checkEXIFOrientation(filePath string, config *imageConfig) []error {
+	name, ok := exifOrientationNames[config.orientation]
+	if !ok {
+		return nil
+	}
+
+	const errFmt = "EXIF orientation %d displays the image %s: rotate the pixels and remove the tag, e.g. with `exiftran -ai` or `mogrify -auto-orient`"
+	return []error{&validationError{
+		File:     filePath,
+		Rule:     "exif-orientation",
+		Err:      fmt.Errorf(errFmt, config.orientation, name),
+		Severity: severityWarning,
+	}}
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// trackedPaths contains the absolute paths of files tracked by git and their
+// parent directories. It is nil unless `-tracked-only` is set.
+var trackedPaths map[string]bool
+
+// loadTrackedPaths consults `git ls-files` to find the files tracked in
+// `rootPath`.
+func loadTrackedPaths(rootPath string) (map[string]bool, error) {
+	out, err := runGit(rootPath, "ls-files", "-z")
+	if err != nil {
+		return nil, err
+	}
+
+	return parsePaths(rootPath, out)
+}
+
+// changedPaths contains the absolute paths of files changed since the
+// `-changed-since` ref and their parent directories. It is nil unless
+// `-changed-since` is set.
+var changedPaths map[string]bool
+
+// loadChangedPaths consults `git diff` and `git ls-files` to find the files in
+// `rootPath` that were added, modified or deleted since `ref`, including the
+// uncommitted and the untracked ones.
+func loadChangedPaths(rootPath, ref string) (map[string]bool, error) {
+	diff, err := runGit(rootPath, "diff", "--name-only", "-z", "--relative", ref, "--", ".")
+	if err != nil {
+		return nil, err
+	}
+
+	untracked, err := runGit(rootPath, "ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return nil, err
+	}
+
+	return parsePaths(rootPath, append(diff, untracked...))
+}
+
+// parsePaths returns the absolute paths of the NUL separated file names in
+// `out`, relative to `rootPath`, and of their parent directories up to
+// `rootPath`.
+func parsePaths(rootPath string, out []byte) (map[string]bool, error) {
+	root, err := filepath.Abs(rootPath)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := map[string]bool{root: true}
+	for _, name := range bytes.Split(out, []byte{0}) {
+		if len(name) == 0 {
+			continue
+		}
+
+		for p := filepath.Join(root, string(name)); !paths[p]; p = filepath.Dir(p) {
+			paths[p] = true
+		}
+	}
+
+	return paths, nil
+}
+
+// isChanged reports whether the file or the directory at `path` changed since
+// the `-changed-since` ref. Everything is considered changed if it isn't set.
+func isChanged(path string) bool {
+	if changedPaths == nil {
+		return true
+	}
+
+	if _, _, ok := mountedPath(path); ok {
+		return true // archives aren't in git work trees
+	}
+
+	abs, err := filepath.Abs(path)
+	return err == nil && changedPaths[abs]
+}
+
+// runGit runs the git command with `args` in `dir` and returns its output.
+func runGit(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if ee, ok := err.(*exec.ExitError); ok {
+		return nil, fmt.Errorf("git %s: %s", args[0], bytes.TrimSpace(ee.Stderr))
+	}
+
+	return out, err
+}
+
+// ignoredPaths match the paths ignored by `.gitignore` files if `-gitignore`
+// is set, and by `.validateignore` files and `-exclude` patterns.
+var ignoredPaths []*gitignore
+
+// isSkipped reports whether the file or the directory at `path` must be
+// excluded from validation.
+func isSkipped(path string) bool {
+	if _, _, ok := mountedPath(path); ok {
+		return isIgnored(path) // archives aren't in git work trees
+	}
+
+	if symlinkPolicy != "follow" && isSymlink(path) {
+		return true // reported by checkSymlinks if the policy is `error`
+	}
+
+	return isExcluded(path)
+}
+
+// isExcluded reports whether the file or the directory at `path` isn't
+// tracked by git or is ignored, according to `-tracked-only`, `-gitignore`,
+// `.validateignore` files and `-exclude` patterns.
+func isExcluded(path string) bool {
+	if trackedPaths == nil && ignoredPaths == nil {
+		return false
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return true
+	}
+
+	if trackedPaths != nil && !trackedPaths[abs] {
+		return true
+	}
+
+	return isIgnored(path)
+}
+
+// isIgnored reports whether the file or the directory at `path` is ignored by
+// `.gitignore` files, `.validateignore` files or `-exclude` patterns.
+func isIgnored(path string) bool {
+	if ignoredPaths == nil {
+		return false
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return true
+	}
+
+	info, err := statFile(path)
+	for _, g := range ignoredPaths {
+		if g.isIgnored(abs, err == nil && info.IsDir()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// stdinPath is the absolute path of the file whose content is read from stdin
+// instead of the disk. It is empty unless `-stdin` is set.
+var (
+	stdinPath    string
+	stdinContent []byte
+)
+
+// isStdinFile reports whether the content of the file at `path` is read from
+// stdin.
+func isStdinFile(path string) bool {
+	if stdinPath == "" {
+		return false
+	}
+
+	abs, err := filepath.Abs(path)
+	return err == nil && abs == stdinPath
+}
+
+// stdinFileInfo describes the file whose content is read from stdin.
+type stdinFileInfo struct{}
+
+func (stdinFileInfo) Name() string       { return filepath.Base(stdinPath) }
+func (stdinFileInfo) Size() int64        { return int64(len(stdinContent)) }
+func (stdinFileInfo) Mode() os.FileMode  { return 0o644 }
+func (stdinFileInfo) ModTime() time.Time { return time.Time{} }
+func (stdinFileInfo) IsDir() bool        { return false }
+func (stdinFileInfo) Sys() interface{}   { return nil }
+
+// readDir is like `ioutil.ReadDir`, but it leaves out the entries excluded
+// from validation. If the file read from stdin belongs to `dirPath`, it is
+// listed even if it doesn't exist on the disk.
+func readDir(dirPath string) ([]os.FileInfo, error) {
+	hasStdinFile := isStdinFile(filepath.Join(dirPath, filepath.Base(stdinPath)))
+	files, err := readDirInfo(dirPath)
+	if err != nil && !(hasStdinFile && os.IsNotExist(err)) {
+		return nil, err
+	}
+
+	included := make([]os.FileInfo, 0, len(files)+1)
+	for _, f := range files {
+		if hasStdinFile && f.Name() == filepath.Base(stdinPath) {
+			continue
+		}
+
+		filePath := filepath.Join(dirPath, f.Name())
+		if isSkipped(filePath) {
+			continue
+		}
+
+		if f.Mode()&os.ModeSymlink != 0 {
+			info, err := os.Stat(filePath)
+			if err != nil {
+				continue // reported by checkSymlinks
+			}
+
+			f = symlinkInfo{FileInfo: info, name: f.Name()}
+		}
+
+		included = append(included, f)
+	}
+
+	if hasStdinFile {
+		included = append(included, stdinFileInfo{})
+		sort.Slice(included, func(i, j int) bool { return included[i].Name() < included[j].Name() })
+	}
+
+	return included, nil
+}
+
+// readFile is like `ioutil.ReadFile`, but it treats files excluded from
+// validation as nonexistent and returns the content read from stdin for the
+// `-stdin-filename` file.
+func readFile(filePath string) ([]byte, error) {
+	if isStdinFile(filePath) {
+		return stdinContent, nil
+	}
+
+	if isSkipped(filePath) {
+		return nil, &os.PathError{Op: "open", Path: filePath, Err: os.ErrNotExist}
+	}
+
+	if fsys, name, ok := mountedPath(filePath); ok {
+		return fs.ReadFile(fsys, name)
+	}
+
+	return ioutil.ReadFile(filePath)
+}
+
+// writeFile is like `ioutil.WriteFile`, but it keeps the permissions of the
+// existing file and refuses to write the files in archives and git refs, and
+// the file read from stdin.
+func writeFile(filePath string, content []byte) error {
+	if _, _, ok := mountedPath(filePath); ok || isStdinFile(filePath) {
+		return fmt.Errorf("can't write the file outside the working tree")
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filePath, content, info.Mode().Perm())
+}
+
+// readDirInfo is like `ioutil.ReadDir`, but it also reads the directories in
+// archives.
+func readDirInfo(dirPath string) ([]os.FileInfo, error) {
+	fsys, name, ok := mountedPath(dirPath)
+	if !ok {
+		return ioutil.ReadDir(dirPath)
+	}
+
+	entries, err := fs.ReadDir(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]os.FileInfo, 0, len(entries))
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, info)
+	}
+
+	return files, nil
+}
+
+// openFile is like `os.Open`, but it also opens the files in archives.
+func openFile(filePath string) (io.ReadCloser, error) {
+	if fsys, name, ok := mountedPath(filePath); ok {
+		return fsys.Open(name)
+	}
+
+	return os.Open(filePath)
+}
+
+// statFile is like `os.Stat`, but it also describes the files in archives.
+func statFile(path string) (os.FileInfo, error) {
+	if fsys, name, ok := mountedPath(path); ok {
+		return fs.Stat(fsys, name)
+	}
+
+	return os.Stat(path)
+}
+
+// walkFiles is like `filepath.Walk`, but it also walks the directories in
+// archives.
+func walkFiles(rootPath string, fn filepath.WalkFunc) error {
+	fsys, name, ok := mountedPath(rootPath)
+	if !ok {
+		return filepath.Walk(rootPath, fn)
+	}
+
+	return fs.WalkDir(fsys, name, func(path string, d fs.DirEntry, err error) error {
+		filePath := filepath.Join(rootPath, filepath.FromSlash(strings.TrimPrefix(path, name)))
+		if err != nil {
+			return fn(filePath, nil, err)
+		}
+
+		info, err := d.Info()
+		return fn(filePath, info, err)
+	})
+}
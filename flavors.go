@@ -0,0 +1,104 @@
+package main
+
+import "path/filepath"
+
+// localeTree is a directory containing locale directories, e.g. the metadata
+// directory of a Gradle product flavor.
+type localeTree struct {
+	root    string // fastlane path containing the tree
+	flavor  string // empty unless the tree belongs to a flavor
+	path    string
+	locales []string
+}
+
+// looksLikeLocale reports whether the directory at `dirPath` seems to contain
+// the metadata of a locale.
+func looksLikeLocale(dirPath string) bool {
+	if playStoreLocales.contains(filepath.Base(dirPath)) {
+		return true
+	}
+
+	for _, p := range []string{
+		layout.textPath(dirPath, "title.txt"),
+		layout.textPath(dirPath, "short_description.txt"),
+		layout.textPath(dirPath, "full_description.txt"),
+		filepath.Join(dirPath, layout.imagesDir),
+		filepath.Join(dirPath, "changelogs"),
+	} {
+		if _, err := statFile(p); err == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// subdirs returns the names of the directories in `dirPath`.
+func subdirs(dirPath string) ([]string, error) {
+	files, err := readDir(dirPath)
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := make([]string, 0, len(files))
+	for _, f := range files {
+		if f.IsDir() {
+			dirs = append(dirs, f.Name())
+		}
+	}
+
+	return dirs, nil
+}
+
+// findLocaleTrees returns the locale trees in `rootPath` according to the
+// `-flavors` mode. With `off`, `rootPath` is the only tree. With `on`, each of
+// its subdirectories is a flavor tree. With `auto`, its subdirectories that
+// don't look like locales but contain locales are flavor trees.
+func findLocaleTrees(rootPath string) ([]localeTree, error) {
+	if flavorMode == "off" {
+		treePath := filepath.Join(rootPath, layout.listingsDir)
+		dirs, err := subdirs(treePath)
+		if err != nil {
+			return nil, err
+		}
+
+		return []localeTree{{root: rootPath, path: treePath, locales: dirs}}, nil
+	}
+
+	dirs, err := subdirs(rootPath)
+	if err != nil {
+		return nil, err
+	}
+
+	root := localeTree{root: rootPath, path: rootPath, locales: make([]string, 0)}
+	flavors := make([]localeTree, 0)
+	for _, d := range dirs {
+		dirPath := filepath.Join(rootPath, d)
+		if flavorMode == "auto" && looksLikeLocale(dirPath) {
+			root.locales = append(root.locales, d)
+			continue
+		}
+
+		locales, err := subdirs(dirPath)
+		if err != nil {
+			return nil, err
+		}
+
+		isFlavor := flavorMode == "on"
+		for _, l := range locales {
+			isFlavor = isFlavor || looksLikeLocale(filepath.Join(dirPath, l))
+		}
+
+		if isFlavor {
+			flavors = append(flavors, localeTree{root: rootPath, flavor: d, path: dirPath, locales: locales})
+		} else {
+			root.locales = append(root.locales, d)
+		}
+	}
+
+	if len(root.locales) == 0 {
+		return flavors, nil
+	}
+
+	return append([]localeTree{root}, flavors...), nil
+}
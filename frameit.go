@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// frameitFont declares a font in the Framefile, optionally restricted to a set
+// of locales.
+type frameitFont struct {
+	Font      string   `json:"font"`
+	Supported []string `json:"supported"`
+}
+
+// frameitText declares the keyword or the title options in the Framefile.
+type frameitText struct {
+	Font  string        `json:"font"`
+	Fonts []frameitFont `json:"fonts"`
+}
+
+// frameitEntry declares the options of the default entry or a data entry in
+// the Framefile.
+type frameitEntry struct {
+	Filter     string       `json:"filter"`
+	Background string       `json:"background"`
+	Keyword    *frameitText `json:"keyword"`
+	Title      *frameitText `json:"title"`
+}
+
+type framefile struct {
+	Default frameitEntry   `json:"default"`
+	Data    []frameitEntry `json:"data"`
+}
+
+// skipFramedScreenshots is set when a Framefile exists, so that screenshots
+// written by frameit are excluded from the screenshot checks.
+var skipFramedScreenshots bool
+
+// isFramedScreenshot reports whether the given file is a screenshot written by
+// frameit.
+func isFramedScreenshot(name string) bool {
+	return strings.HasSuffix(strings.TrimSuffix(name, filepath.Ext(name)), "_framed")
+}
+
+// checkFramefile checks the frameit config at `framefilePath` if it exists. It
+// verifies that the referenced fonts and backgrounds exist, that the locales
+// referenced by fonts are present in the metadata, and that the locales with
+// screenshots have the `keyword.strings` and `title.strings` files that the
+// Framefile relies on.
+func checkFramefile(framefilePath string, locales []string) []error {
+	content, err := ioutil.ReadFile(framefilePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil // frameit is optional
+		}
+
+		const errFmt = "failed to read file %q: %w"
+		return []error{fmt.Errorf(errFmt, framefilePath, err)}
+	}
+
+	ff := framefile{}
+	if err := json.Unmarshal(content, &ff); err != nil {
+		return []error{&validationError{
+			File: framefilePath,
+			Rule: "framefile",
+			Err:  fmt.Errorf("invalid Framefile: %w", err),
+		}}
+	}
+
+	baseDir := filepath.Dir(framefilePath)
+	errs := make([]error, 0)
+	checkFile := func(entry, option, path string) {
+		if path == "" {
+			return
+		}
+
+		if _, err := os.Stat(filepath.Join(baseDir, path)); err != nil {
+			const errFmt = "%s.%s: referenced file %q doesn't exist"
+			errs = append(errs, &validationError{
+				File: framefilePath,
+				Rule: "framefile",
+				Err:  fmt.Errorf(errFmt, entry, option, path),
+			})
+		}
+	}
+
+	checkText := func(entry, option string, text *frameitText) {
+		if text == nil {
+			return
+		}
+
+		checkFile(entry, option+".font", text.Font)
+		for i, font := range text.Fonts {
+			fontOption := fmt.Sprintf("%s.fonts[%d]", option, i)
+			checkFile(entry, fontOption+".font", font.Font)
+			for _, locale := range font.Supported {
+				if !containsString(locales, locale) {
+					const errFmt = "%s.%s.supported: locale %q isn't present in the metadata"
+					errs = append(errs, &validationError{
+						File: framefilePath,
+						Rule: "framefile",
+						Err:  fmt.Errorf(errFmt, entry, fontOption, locale),
+					})
+				}
+			}
+		}
+	}
+
+	declared := make(map[string]bool)
+	entries := append([]frameitEntry{ff.Default}, ff.Data...)
+	for i, e := range entries {
+		name := "default"
+		if i > 0 {
+			name = fmt.Sprintf("data[%d]", i-1)
+		}
+
+		checkFile(name, "background", e.Background)
+		checkText(name, "keyword", e.Keyword)
+		checkText(name, "title", e.Title)
+		declared["keyword"] = declared["keyword"] || e.Keyword != nil
+		declared["title"] = declared["title"] || e.Title != nil
+	}
+
+	// frameit reads the texts of the declared options from the `.strings`
+	// files in the screenshot directory of each locale
+	for _, option := range []string{"keyword", "title"} {
+		if !declared[option] {
+			continue
+		}
+
+		for _, locale := range locales {
+			if info, err := os.Stat(filepath.Join(baseDir, locale)); err != nil || !info.IsDir() {
+				continue // frameit only frames the locales with screenshots
+			}
+
+			stringsPath := filepath.Join(baseDir, locale, option+".strings")
+			if _, err := os.Stat(stringsPath); err != nil {
+				const errFmt = "file is missing: the Framefile declares the %s option"
+				errs = append(errs, &validationError{
+					File: stringsPath,
+					Rule: "framefile",
+					Err:  fmt.Errorf(errFmt, option),
+				})
+			}
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// countCharacters returns the length of `text` according to `-count-mode`:
+// the number of runes or of grapheme clusters, i.e. user-perceived characters,
+// as Play Console counts them.
+func countCharacters(text string) int {
+	if countMode == "rune" {
+		return utf8.RuneCountInString(text)
+	}
+
+	return countGraphemes(text)
+}
+
+// countGraphemes returns the number of extended grapheme clusters in `text`
+// following the rules of Unicode Standard Annex #29 that matter for store
+// listings: CRLF, combining marks, emoji modifier and ZWJ sequences, flags and
+// Hangul syllables. Prepended concatenation marks aren't supported.
+func countGraphemes(text string) int {
+	count := 0
+	var prev rune = -1
+	riCount := 0          // consecutive regional indicators before the rune
+	pictographic := false // the cluster is an emoji sequence so far
+	for _, r := range text {
+		if prev < 0 || isGraphemeBreak(prev, r, riCount, pictographic) {
+			count++
+			pictographic = false
+		}
+
+		if isRegionalIndicator(r) {
+			riCount++
+		} else {
+			riCount = 0
+		}
+
+		if isEmoji(r) && !isGraphemeExtend(r) && r != 0x200D {
+			pictographic = true
+		}
+
+		prev = r
+	}
+
+	return count
+}
+
+// isGraphemeBreak reports whether there is a grapheme cluster boundary between
+// `prev` and `r`.
+func isGraphemeBreak(prev, r rune, riCount int, pictographic bool) bool {
+	switch {
+	case prev == '\r' && r == '\n': // GB3
+		return false
+	case unicode.IsControl(prev) || unicode.IsControl(r): // GB4, GB5
+		return true
+	case isHangulL(prev) && (isHangulL(r) || isHangulV(r) || isHangulLV(r) || isHangulLVT(r)): // GB6
+		return false
+	case (isHangulLV(prev) || isHangulV(prev)) && (isHangulV(r) || isHangulT(r)): // GB7
+		return false
+	case (isHangulLVT(prev) || isHangulT(prev)) && isHangulT(r): // GB8
+		return false
+	case isGraphemeExtend(r) || r == 0x200D || unicode.Is(unicode.Mc, r): // GB9, GB9a
+		return false
+	case prev == 0x200D && pictographic && isEmoji(r): // GB11
+		return false
+	case isRegionalIndicator(prev) && isRegionalIndicator(r): // GB12, GB13
+		return riCount%2 == 0
+	}
+
+	return true // GB999
+}
+
+// isGraphemeExtend reports whether `r` extends the preceding character, e.g. a
+// combining mark, a variation selector or an emoji modifier.
+func isGraphemeExtend(r rune) bool {
+	switch {
+	case unicode.In(r, unicode.Mn, unicode.Me):
+		return true
+	case r >= 0xFE00 && r <= 0xFE0F, r >= 0xE0100 && r <= 0xE01EF: // variation selectors
+		return true
+	case r >= 0x1F3FB && r <= 0x1F3FF: // emoji modifiers
+		return true
+	case r >= 0xE0020 && r <= 0xE007F: // tags
+		return true
+	}
+
+	return false
+}
+
+func isRegionalIndicator(r rune) bool { return r >= 0x1F1E6 && r <= 0x1F1FF }
+
+func isHangulL(r rune) bool {
+	return r >= 0x1100 && r <= 0x115F || r >= 0xA960 && r <= 0xA97C
+}
+
+func isHangulV(r rune) bool {
+	return r >= 0x1160 && r <= 0x11A7 || r >= 0xD7B0 && r <= 0xD7C6
+}
+
+func isHangulT(r rune) bool {
+	return r >= 0x11A8 && r <= 0x11FF || r >= 0xD7CB && r <= 0xD7FB
+}
+
+func isHangulLV(r rune) bool {
+	return r >= 0xAC00 && r <= 0xD7A3 && (r-0xAC00)%28 == 0
+}
+
+func isHangulLVT(r rune) bool {
+	return r >= 0xAC00 && r <= 0xD7A3 && (r-0xAC00)%28 != 0
+}
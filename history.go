@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// ruleCounts is the number of errors and warnings reported by a rule.
+type ruleCounts struct {
+	Errors   int `json:"errors"`
+	Warnings int `json:"warnings"`
+}
+
+// runSummary is a line in the history file.
+type runSummary struct {
+	Time     time.Time             `json:"time"`
+	Errors   int                   `json:"errors"`
+	Warnings int                   `json:"warnings"`
+	Rules    map[string]ruleCounts `json:"rules"`
+}
+
+// regression is an increase in the number of issues reported by a rule
+// between two consecutive runs.
+type regression struct {
+	Time     time.Time  `json:"time"`
+	Rule     string     `json:"rule"`
+	Previous ruleCounts `json:"previous"`
+	Current  ruleCounts `json:"current"`
+}
+
+func newRunSummary(r *report) *runSummary {
+	s := &runSummary{
+		Time:     time.Now().UTC(),
+		Errors:   r.Errors,
+		Warnings: r.Warnings,
+		Rules:    make(map[string]ruleCounts),
+	}
+
+	for _, i := range r.Issues {
+		c := s.Rules[i.Rule]
+		if i.Severity == "warning" {
+			c.Warnings++
+		} else {
+			c.Errors++
+		}
+
+		s.Rules[i.Rule] = c
+	}
+
+	return s
+}
+
+// appendHistory appends the summary of the given report to the history file
+// at `path` as a JSON line.
+func appendHistory(path string, r *report) error {
+	content, err := json.Marshal(newRunSummary(r))
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(append(content, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// readHistory returns all run summaries in the history file at `path`.
+func readHistory(path string) ([]*runSummary, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+	runs := make([]*runSummary, 0)
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		s := &runSummary{}
+		if err := json.Unmarshal(scanner.Bytes(), s); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		runs = append(runs, s)
+	}
+
+	return runs, scanner.Err()
+}
+
+// findRegressions returns the rules that reported more errors or warnings in a
+// run than in the previous one.
+func findRegressions(runs []*runSummary) []regression {
+	regressions := make([]regression, 0)
+	for i := 1; i < len(runs); i++ {
+		rules := make([]string, 0, len(runs[i].Rules))
+		for rule := range runs[i].Rules {
+			rules = append(rules, rule)
+		}
+
+		sort.Strings(rules)
+		for _, rule := range rules {
+			prev, curr := runs[i-1].Rules[rule], runs[i].Rules[rule]
+			if curr.Errors > prev.Errors || curr.Warnings > prev.Warnings {
+				regressions = append(regressions, regression{
+					Time:     runs[i].Time,
+					Rule:     rule,
+					Previous: prev,
+					Current:  curr,
+				})
+			}
+		}
+	}
+
+	return regressions
+}
+
+// trendCommand prints the run summaries in the history file and the
+// regressions between consecutive runs.
+func trendCommand(args []string) {
+	fs := flag.NewFlagSet("trend", flag.ExitOnError)
+	last := fs.Int("last", 0, "only consider the last N runs; 0 considers all runs")
+	fs.Parse(args)
+
+	if historyPath == "" {
+		fmt.Fprintln(os.Stderr, "trend requires the -history flag")
+		os.Exit(2)
+	}
+
+	runs, err := readHistory(historyPath)
+	if err != nil {
+		const errFmt = "failed to read history %q: %s\n"
+		fmt.Fprintf(os.Stderr, errFmt, historyPath, err)
+		os.Exit(1)
+	}
+
+	if *last > 0 && len(runs) > *last {
+		runs = runs[len(runs)-*last:]
+	}
+
+	regressions := findRegressions(runs)
+	if outputFormat == "json" {
+		printJSON(struct {
+			Runs        []*runSummary `json:"runs"`
+			Regressions []regression  `json:"regressions"`
+		}{runs, regressions})
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tERRORS\tWARNINGS")
+	for _, r := range runs {
+		fmt.Fprintf(w, "%s\t%d\t%d\n", r.Time.Format(time.RFC3339), r.Errors, r.Warnings)
+	}
+
+	w.Flush()
+	fmt.Printf("\nregressions (%d):\n", len(regressions))
+	for _, r := range regressions {
+		const regressionFmt = "  %s: %s: errors %d -> %d, warnings %d -> %d\n"
+		fmt.Printf(regressionFmt, r.Time.Format(time.RFC3339), r.Rule,
+			r.Previous.Errors, r.Current.Errors, r.Previous.Warnings, r.Current.Warnings)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"unicode/utf16"
+)
+
+// maxICCProfileBytes caps the size of the decompressed ICC profile of PNG
+// images, so that a small iCCP chunk can't expand to gigabytes. Real profiles
+// are well below 1 MB.
+const maxICCProfileBytes = 4 << 20
+
+// imageColorProfile returns the description of the ICC profile embedded in the
+// PNG or JPEG image at `filePath`, "sRGB" for PNGs with an sRGB chunk, or an
+// empty string for untagged images. It only reads the chunks and the marker
+// segments preceding the image data.
+func imageColorProfile(filePath string, config *imageConfig) (string, error) {
+	var profile []byte
+	switch config.format {
+	case "png":
+		for _, c := range config.chunks {
+			switch c.kind {
+			case "sRGB":
+				return "sRGB", nil
+			case "iCCP":
+				// null-terminated profile name, compression method, zlib stream
+				i := bytes.IndexByte(c.data, 0)
+				if i < 0 || i+2 > len(c.data) {
+					return "", fmt.Errorf("invalid PNG iCCP chunk")
+				}
+
+				r, err := zlib.NewReader(bytes.NewReader(c.data[i+2:]))
+				if err != nil {
+					return "", err
+				}
+
+				if profile, err = ioutil.ReadAll(io.LimitReader(r, maxICCProfileBytes+1)); err != nil {
+					return "", err
+				}
+
+				if len(profile) > maxICCProfileBytes {
+					const errFmt = "embedded ICC profile is too large: expected<=%d bytes"
+					return "", &validationError{
+						File: filePath,
+						Rule: "image-too-large",
+						Err:  fmt.Errorf(errFmt, maxICCProfileBytes),
+					}
+				}
+
+				if description := iccDescription(profile); description != "" {
+					return description, nil
+				}
+
+				return string(c.data[:i]), nil
+			}
+		}
+	case "jpeg":
+		if config.segmentsErr != nil {
+			return "", config.segmentsErr
+		}
+
+		// large profiles span several APP2 segments, which are assumed to be in
+		// sequence order
+		for _, s := range config.segments {
+			if s.marker == 0xE2 && len(s.data) > 14 && string(s.data[:12]) == "ICC_PROFILE\x00" {
+				profile = append(profile, s.data[14:]...)
+			}
+		}
+
+		if profile != nil {
+			if description := iccDescription(profile); description != "" {
+				return description, nil
+			}
+
+			return "unnamed ICC profile", nil
+		}
+	}
+
+	return "", nil
+}
+
+// iccDescription returns the profile description (`desc`) tag of the ICC
+// profile, in the ICC v2 `desc` or the ICC v4 `mluc` format.
+func iccDescription(profile []byte) string {
+	if len(profile) < 132 {
+		return ""
+	}
+
+	count := int(binary.BigEndian.Uint32(profile[128:]))
+	for i := 0; i < count && 132+12*(i+1) <= len(profile); i++ {
+		entry := profile[132+12*i:]
+		offset, size := int(binary.BigEndian.Uint32(entry[4:])), int(binary.BigEndian.Uint32(entry[8:]))
+		if string(entry[:4]) != "desc" || offset < 0 || size < 12 || offset+size > len(profile) {
+			continue
+		}
+
+		tag := profile[offset : offset+size]
+		switch string(tag[:4]) {
+		case "desc":
+			n := int(binary.BigEndian.Uint32(tag[8:]))
+			if n > 0 && 12+n <= len(tag) {
+				return strings.TrimRight(string(tag[12:12+n]), "\x00")
+			}
+		case "mluc":
+			// the first record: language, country, length and offset
+			if len(tag) < 28 {
+				return ""
+			}
+
+			n, o := int(binary.BigEndian.Uint32(tag[20:])), int(binary.BigEndian.Uint32(tag[24:]))
+			if o < 0 || n < 0 || o+n > len(tag) {
+				return ""
+			}
+
+			u := make([]uint16, n/2)
+			for j := range u {
+				u[j] = binary.BigEndian.Uint16(tag[o+2*j:])
+			}
+
+			return strings.TrimRight(string(utf16.Decode(u)), "\x00")
+		}
+	}
+
+	return ""
+}
+
+// isSRGBProfile reports whether the profile description names an sRGB profile,
+// e.g. "sRGB IEC61966-2.1" or "sRGB built-in".
+func isSRGBProfile(description string) bool {
+	d := strings.ToLower(description)
+	return strings.Contains(d, "srgb") || strings.Contains(d, "61966-2")
+}
+
+// checkColorProfile warns if the image at `filePath` embeds a color profile
+// other than sRGB with `-require-srgb`. Play converts the images to sRGB, which
+// makes those with a wide gamut profile, e.g. Display P3 or Adobe RGB, look
+// washed out.
+func checkColorProfile(filePath string, config *imageConfig) []error {
+	if !requireSRGB {
+		return nil
+	}
+
+	description, err := imageColorProfile(filePath, config)
+	if ve := (*validationError)(nil); errors.As(err, &ve) {
+		return []error{ve}
+	}
+
+	if err != nil || description == "" || isSRGBProfile(description) {
+		return nil // untagged images are assumed to be sRGB
+	}
+
+	const errFmt = "image has the %q color profile: expected sRGB or none, since Google Play's conversion may wash out its colors"
+	return []error{&validationError{
+		File:     filePath,
+		Rule:     "color-profile",
+		Err:      fmt.Errorf(errFmt, description),
+		Severity: severityWarning,
+	}}
+}
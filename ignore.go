@@ -0,0 +1,221 @@
+package main
+
+import (
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// ignorePattern is a single pattern in a gitignore-style file.
+type ignorePattern struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// parseIgnorePatterns parses patterns in the gitignore format, one per line.
+func parseIgnorePatterns(content string) []ignorePattern {
+	patterns := make([]ignorePattern, 0)
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if !strings.HasSuffix(line, "\\ ") {
+			line = strings.TrimRight(line, " ")
+		}
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if p, ok := newIgnorePattern(line); ok {
+			patterns = append(patterns, p)
+		}
+	}
+
+	return patterns
+}
+
+// newIgnorePattern compiles a single gitignore-style pattern.
+func newIgnorePattern(pattern string) (ignorePattern, bool) {
+	p := ignorePattern{}
+	if strings.HasPrefix(pattern, "!") {
+		p.negate = true
+		pattern = pattern[1:]
+	}
+
+	if strings.HasSuffix(pattern, "/") {
+		p.dirOnly = true
+		pattern = strings.TrimSuffix(pattern, "/")
+	}
+
+	// patterns with a slash at the beginning or in the middle are relative to
+	// the directory of the ignore file. Others match at any level.
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	if pattern == "" {
+		return p, false
+	}
+
+	re := strings.Builder{}
+	re.WriteString("^")
+	if !anchored {
+		re.WriteString("(?:.*/)?")
+	}
+
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case strings.HasPrefix(pattern[i:], "**/") && (i == 0 || pattern[i-1] == '/'):
+			re.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**") && i+2 == len(pattern) && (i == 0 || pattern[i-1] == '/'):
+			re.WriteString(".*")
+			i++
+		case c == '*':
+			re.WriteString("[^/]*")
+		case c == '?':
+			re.WriteString("[^/]")
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			re.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		case c == '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				re.WriteString(regexp.QuoteMeta("["))
+				continue
+			}
+
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+
+			re.WriteString("[" + strings.ReplaceAll(class, "\\", "\\\\") + "]")
+			i += end + 1
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	re.WriteString("$")
+	compiled, err := regexp.Compile(re.String())
+	if err != nil {
+		return p, false
+	}
+
+	p.re = compiled
+	return p, true
+}
+
+// match reports whether the pattern matches `relPath`, a slash-separated path
+// relative to the directory of the ignore file.
+func (p ignorePattern) match(relPath string, isDir bool) bool {
+	return (isDir || !p.dirOnly) && p.re.MatchString(relPath)
+}
+
+// gitignore matches paths against the ignore files, e.g. `.gitignore`, in
+// `root` and its subdirectories.
+type gitignore struct {
+	root     string
+	name     string // name of the ignore files
+	patterns map[string][]ignorePattern
+	mu       sync.Mutex // guards patterns, which locales add to concurrently
+}
+
+// newGitignore returns a gitignore for the work tree containing `path`. If
+// `path` isn't in a git work tree, only the `.gitignore` files in `path` and
+// its subdirectories are considered.
+func newGitignore(path string) (*gitignore, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	root := abs
+	for dir := abs; ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			root = dir
+			break
+		}
+
+		if dir == filepath.Dir(dir) {
+			break
+		}
+	}
+
+	return &gitignore{root: root, name: ".gitignore", patterns: make(map[string][]ignorePattern)}, nil
+}
+
+// newValidateIgnore returns a gitignore for the `.validateignore` files in the
+// metadata directory at `path` and its subdirectories. The `excludes` patterns
+// apply as if they were at the end of the `.validateignore` file in `path`.
+func newValidateIgnore(path string, excludes []string) (*gitignore, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &gitignore{root: abs, name: ".validateignore", patterns: make(map[string][]ignorePattern)}
+	patterns := append(g.dirPatterns(abs), parseIgnorePatterns(strings.Join(excludes, "\n"))...)
+	g.patterns[abs] = patterns
+	return g, nil
+}
+
+// dirPatterns returns the patterns in the ignore file of `dir`.
+func (g *gitignore) dirPatterns(dir string) []ignorePattern {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if p, ok := g.patterns[dir]; ok {
+		return p
+	}
+
+	content, err := readIgnoreFile(filepath.Join(dir, g.name))
+	if err != nil {
+		g.patterns[dir] = nil
+		return nil
+	}
+
+	g.patterns[dir] = parseIgnorePatterns(string(content))
+	return g.patterns[dir]
+}
+
+// isIgnored reports whether the absolute path `path`, or any of its parent
+// directories, is ignored.
+func (g *gitignore) isIgnored(path string, isDir bool) bool {
+	rel, err := filepath.Rel(g.root, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false
+	}
+
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	for i := range parts {
+		ignored := false
+		for j := 0; j <= i; j++ {
+			dir := filepath.Join(g.root, filepath.Join(parts[:j]...))
+			relPath := strings.Join(parts[j:i+1], "/")
+			for _, p := range g.dirPatterns(dir) {
+				if p.match(relPath, isDir || i < len(parts)-1) {
+					ignored = !p.negate
+				}
+			}
+		}
+
+		if ignored {
+			return true
+		}
+	}
+
+	return false
+}
+
+// readIgnoreFile reads the ignore file at `filePath`, which may be in an
+// archive or a git ref. Unlike readFile, it doesn't consult the ignore files.
+func readIgnoreFile(filePath string) ([]byte, error) {
+	if fsys, name, ok := mountedPath(filePath); ok {
+		return fs.ReadFile(fsys, name)
+	}
+
+	return ioutil.ReadFile(filePath)
+}
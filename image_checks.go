@@ -0,0 +1,461 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"math/bits"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// checkNinePatch reports an error if the given image is a nine-patch file. They
+// belong in the app resources, and Play renders their patch markers as visible
+// black lines.
+func checkNinePatch(filePath string) []error {
+	if !strings.HasSuffix(strings.ToLower(filePath), ".9.png") {
+		return nil
+	}
+
+	return []error{&validationError{
+		File: filePath,
+		Rule: "nine-patch",
+		Err:  fmt.Errorf("nine-patch images belong in app resources: Google Play renders their patch markers as visible lines"),
+	}}
+}
+
+// sniffUnsupportedImage returns the name of the image format of `content` if
+// it is one that Play rejects although image editors commonly export it, e.g.
+// WebP, or an empty string otherwise.
+func sniffUnsupportedImage(content []byte) string {
+	head := content
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+
+	switch text := bytes.TrimSpace(bytes.TrimPrefix(head, []byte{0xEF, 0xBB, 0xBF})); {
+	case bytes.HasPrefix(content, []byte("GIF87a")), bytes.HasPrefix(content, []byte("GIF89a")):
+		return "GIF"
+	case len(content) >= 12 && string(content[:4]) == "RIFF" && string(content[8:12]) == "WEBP":
+		return "WebP"
+	case len(content) >= 14 && string(content[:2]) == "BM":
+		return "BMP"
+	case bytes.HasPrefix(content, []byte("II*\x00")), bytes.HasPrefix(content, []byte("MM\x00*")):
+		return "TIFF"
+	case isFtypBrand(content, "heic", "heix", "heim", "heis", "hevc", "hevx", "mif1", "msf1"):
+		return "HEIC"
+	case isFtypBrand(content, "avif", "avis"):
+		return "AVIF"
+	case bytes.HasPrefix(text, []byte("<")) && bytes.Contains(bytes.ToLower(text), []byte("<svg")):
+		return "SVG"
+	}
+
+	return ""
+}
+
+// isFtypBrand reports whether `content` is an ISO base media file, e.g. HEIF,
+// with one of the given major brands.
+func isFtypBrand(content []byte, brands ...string) bool {
+	return len(content) >= 12 && string(content[4:8]) == "ftyp" && containsString(brands, string(content[8:12]))
+}
+
+// imageFormatError returns the validation error for the image `content` at
+// `filePath` if it is in a format that Play rejects, e.g. WebP, so that it is
+// reported by name rather than as an unknown format.
+func imageFormatError(filePath string, content []byte) error {
+	kind := sniffUnsupportedImage(content)
+	if kind == "" {
+		return nil
+	}
+
+	const errFmt = "Google Play only accepts JPEG and 24-bit PNG images: found %s"
+	return &validationError{
+		File: filePath,
+		Rule: "image-format",
+		Err:  fmt.Errorf(errFmt, kind),
+	}
+}
+
+// checkInterlacing warns if the image at `filePath` is an interlaced PNG or a
+// progressive JPEG with `-warn-interlaced`, since Play intermittently fails to
+// process them.
+func checkInterlacing(filePath string, config *imageConfig) []error {
+	if !warnInterlaced {
+		return nil
+	}
+
+	var errMsg error
+	switch config.format {
+	case "png":
+		if ihdr, err := pngHeader(config.chunks); err == nil && ihdr[12] == 1 {
+			errMsg = fmt.Errorf("PNG is interlaced, which Google Play may fail to process: re-save it without interlacing, e.g. with `optipng -i0`")
+		}
+	case "jpeg":
+		if config.segmentsErr == nil && isProgressiveJPEG(config.segments) {
+			errMsg = fmt.Errorf("JPEG is progressive, which Google Play may fail to process: re-encode it as baseline, e.g. with `jpegtran -copy all -outfile <out> <in>`")
+		}
+	}
+
+	if errMsg == nil {
+		return nil
+	}
+
+	return []error{&validationError{
+		File:     filePath,
+		Rule:     "interlaced-image",
+		Err:      errMsg,
+		Severity: severityWarning,
+	}}
+}
+
+// checkImageIntegrity reports the error of fully decoding the image at
+// `filePath` with `-deep-image-check`, to catch truncated and corrupt files,
+// which pass the header checks and then fail to upload.
+func checkImageIntegrity(filePath string, err error) []error {
+	if err == nil || errors.As(err, new(*validationError)) {
+		return nil // already reported by getImageConfig if it's a validation error
+	}
+
+	const errFmt = "image is corrupt and may fail to upload: %w"
+	return []error{&validationError{
+		File: filePath,
+		Rule: "image-corrupt",
+		Err:  fmt.Errorf(errFmt, err),
+	}}
+}
+
+// checkImageFileSize checks that the image at `filePath` is at most `maxBytes`
+// large, since Play rejects larger uploads. 0 disables the check.
+func checkImageFileSize(filePath string, maxBytes int64) []error {
+	info, err := statFile(filePath)
+	if err != nil || maxBytes <= 0 || info.Size() <= maxBytes {
+		return nil
+	}
+
+	const errFmt = "file is too large for Google Play: expected<=%s, got=%s (%d bytes)"
+	return []error{&validationError{
+		File: filePath,
+		Rule: "image-file-size",
+		Err:  fmt.Errorf(errFmt, formatByteSize(maxBytes), formatByteSize(info.Size()), info.Size()),
+	}}
+}
+
+// formatByteSize formats `n` bytes in the largest binary unit with one decimal,
+// e.g. "1.5 MB", like Play Console does.
+func formatByteSize(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return strings.TrimSuffix(strconv.FormatFloat(float64(n)/(1<<20), 'f', 1, 64), ".0") + " MB"
+	case n >= 1<<10:
+		return strings.TrimSuffix(strconv.FormatFloat(float64(n)/(1<<10), 'f', 1, 64), ".0") + " KB"
+	}
+
+	return strconv.FormatInt(n, 10) + " bytes"
+}
+
+// checkIconAlpha checks the icon against the `-icon-alpha` policy. Play accepts
+// transparent icons, but some teams require opaque ones for consistency with
+// the adaptive launcher icon, or transparent ones for its shape.
+func checkIconAlpha(filePath string, config *imageConfig) []error {
+	var errMsg error
+	switch iconAlpha {
+	case "require-opaque":
+		if !config.opaque {
+			errMsg = fmt.Errorf("icon must be fully opaque: found transparent pixels")
+		}
+	case "require-alpha":
+		if config.format == "png" && !hasPNGAlpha(config.chunks) {
+			errMsg = fmt.Errorf("icon must have an alpha channel")
+		}
+	}
+
+	if errMsg == nil {
+		return nil
+	}
+
+	return []error{&validationError{
+		File: filePath,
+		Rule: "icon-alpha",
+		Err:  errMsg,
+	}}
+}
+
+// checkIconPadding warns if more than `iconPaddingThreshold` of the icon's
+// canvas is a fully transparent border. Heavily padded icons render tiny on the
+// store and in launchers.
+func checkIconPadding(filePath string, config *imageConfig) []error {
+	if iconPaddingThreshold <= 0 {
+		return nil
+	}
+
+	img, err := config.decode()
+	if err != nil {
+		return nil // already reported by checkImages
+	}
+
+	bounds := img.Bounds()
+	content := image.Rectangle{}
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if _, _, _, a := img.At(x, y).RGBA(); a > 0 {
+				content = content.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+
+	total := bounds.Dx() * bounds.Dy()
+	if total == 0 {
+		return nil
+	}
+
+	padding := float64(total-content.Dx()*content.Dy()) / float64(total)
+	if padding > iconPaddingThreshold {
+		const errFmt = "%.0f%% of the icon is transparent padding: expected at most %.0f%%"
+		return []error{&validationError{
+			File:     filePath,
+			Rule:     "icon-padding",
+			Err:      fmt.Errorf(errFmt, padding*100, iconPaddingThreshold*100),
+			Severity: severityWarning,
+		}}
+	}
+
+	return nil
+}
+
+// checkLetterbox warns if the screenshot has large uniform black or white bars
+// on its edges, a symptom of capturing at the wrong aspect ratio and padding.
+// Play's carousel makes such screenshots look broken.
+func checkLetterbox(filePath string, img image.Image) []error {
+	if letterboxThreshold <= 0 || img == nil {
+		return nil
+	}
+
+	b := img.Bounds()
+	row := func(y int) image.Rectangle { return image.Rect(b.Min.X, y, b.Max.X, y+1) }
+	col := func(x int) image.Rectangle { return image.Rect(x, b.Min.Y, x+1, b.Max.Y) }
+
+	top, bottom, left, right := 0, 0, 0, 0
+	for y := b.Min.Y; y < b.Max.Y && isUniformBar(img, row(y)); y++ {
+		top++
+	}
+
+	for y := b.Max.Y - 1; y >= b.Min.Y+top && isUniformBar(img, row(y)); y-- {
+		bottom++
+	}
+
+	for x := b.Min.X; x < b.Max.X && isUniformBar(img, col(x)); x++ {
+		left++
+	}
+
+	for x := b.Max.X - 1; x >= b.Min.X+left && isUniformBar(img, col(x)); x-- {
+		right++
+	}
+
+	errs := make([]error, 0)
+	const errFmt = "screenshot seems letterboxed: %dpx of its %s is uniform bars"
+	if float64(top+bottom) >= letterboxThreshold*float64(b.Dy()) {
+		errs = append(errs, &validationError{
+			File:     filePath,
+			Rule:     "screenshot-letterbox",
+			Err:      fmt.Errorf(errFmt, top+bottom, "height"),
+			Severity: severityWarning,
+		})
+	}
+
+	if float64(left+right) >= letterboxThreshold*float64(b.Dx()) {
+		errs = append(errs, &validationError{
+			File:     filePath,
+			Rule:     "screenshot-letterbox",
+			Err:      fmt.Errorf(errFmt, left+right, "width"),
+			Severity: severityWarning,
+		})
+	}
+
+	return errs
+}
+
+// isUniformBar reports whether all pixels of `img` in `r` are (nearly) the same
+// black or white colour.
+func isUniformBar(img image.Image, r image.Rectangle) bool {
+	const tolerance = 16 << 8
+	near := func(a, b uint32) bool { return a+tolerance >= b && b+tolerance >= a }
+	isBlackOrWhite := func(r, g, b uint32) bool {
+		return (r < tolerance && g < tolerance && b < tolerance) ||
+			(r > 0xffff-tolerance && g > 0xffff-tolerance && b > 0xffff-tolerance)
+	}
+
+	r0, g0, b0, _ := img.At(r.Min.X, r.Min.Y).RGBA()
+	if !isBlackOrWhite(r0, g0, b0) {
+		return false
+	}
+
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			r1, g1, b1, _ := img.At(x, y).RGBA()
+			if !near(r0, r1) || !near(g0, g1) || !near(b0, b1) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// checkRequiredAssets checks that the locale has all graphics that the config
+// declares mandatory for it.
+func checkRequiredAssets(localePath string) []error {
+	locale := filepath.Base(localePath)
+	imagesPath := filepath.Join(localePath, layout.imagesDir)
+	errs := make([]error, 0)
+	for _, rule := range cfg.RequiredAssets {
+		if rule.DefaultLocale && locale != defaultLocale {
+			continue
+		}
+
+		if len(rule.Locales) > 0 && !matchesAnyGlob(rule.Locales, locale) {
+			continue
+		}
+
+		for _, name := range rule.Images {
+			if !layout.hasImage(imagesPath, name) {
+				const errFmt = "required image %q is missing"
+				errs = append(errs, &validationError{
+					File: imagesPath,
+					Rule: "required-asset",
+					Err:  fmt.Errorf(errFmt, name),
+				})
+			}
+		}
+
+		for dir, minCount := range rule.Screenshots {
+			screenshotsPath := layout.imagePath(imagesPath, dir)
+			count := 0
+			screenshots, _ := readDir(screenshotsPath)
+			for _, f := range screenshots {
+				if !f.IsDir() && !(skipFramedScreenshots && isFramedScreenshot(f.Name())) {
+					count++
+				}
+			}
+
+			if count < minCount {
+				const errFmt = "expected at least %d screenshots: got=%d"
+				errs = append(errs, &validationError{
+					File: screenshotsPath,
+					Rule: "required-asset",
+					Err:  fmt.Errorf(errFmt, minCount, count),
+				})
+			}
+		}
+	}
+
+	return errs
+}
+
+// screenshotDigest identifies the content of a screenshot for
+// checkDuplicateScreenshots.
+type screenshotDigest struct {
+	path     string
+	checksum string
+	// hash is the perceptual hash of the image, if it was decoded.
+	hash   uint64
+	hashed bool
+}
+
+// digestScreenshot returns the digest of the screenshot at `filePath`, hashing
+// the decoded `img` if it isn't nil.
+func digestScreenshot(filePath string, config *imageConfig, img image.Image) screenshotDigest {
+	checksum := sha256.Sum256(config.content)
+	d := screenshotDigest{path: filePath, checksum: hex.EncodeToString(checksum[:])}
+	if img != nil {
+		d.hash, d.hashed = differenceHash(img), true
+	}
+
+	return d
+}
+
+// checkDuplicateScreenshots warns about the screenshots that repeat an earlier
+// one in the same directory, either byte for byte or, within
+// `-duplicate-screenshot-distance`, by their perceptual hashes. A repeated
+// screenshot usually fills a slot with the wrong picture.
+func checkDuplicateScreenshots(digests []screenshotDigest) []error {
+	errs := make([]error, 0)
+	checksums := make(map[string]string, len(digests))
+	hashed := make([]screenshotDigest, 0, len(digests))
+	for _, d := range digests {
+		if original, ok := checksums[d.checksum]; ok {
+			const errFmt = "screenshot is identical to %s"
+			errs = append(errs, &validationError{
+				File:     d.path,
+				Rule:     "duplicate-screenshot",
+				Err:      fmt.Errorf(errFmt, filepath.Base(original)),
+				Severity: severityWarning,
+			})
+
+			continue
+		}
+
+		checksums[d.checksum] = d.path
+		if duplicateDistance < 0 || !d.hashed {
+			continue
+		}
+
+		for _, h := range hashed {
+			if distance := bits.OnesCount64(d.hash ^ h.hash); distance <= duplicateDistance {
+				const errFmt = "screenshot looks the same as %s: perceptual hashes differ in %d bits"
+				errs = append(errs, &validationError{
+					File:     d.path,
+					Rule:     "duplicate-screenshot",
+					Err:      fmt.Errorf(errFmt, filepath.Base(h.path), distance),
+					Severity: severityWarning,
+				})
+
+				break
+			}
+		}
+
+		hashed = append(hashed, d)
+	}
+
+	return errs
+}
+
+// differenceHash returns the 64-bit difference hash of `img`: it shrinks the
+// image to 9x8 cells of average luminance, and sets a bit for each cell that
+// is brighter than its right neighbour. Resized or recompressed copies of an
+// image have nearly the same hash.
+func differenceHash(img image.Image) uint64 {
+	const cols, rows = 9, 8
+	var sums [rows][cols]float64
+	var counts [rows][cols]int
+
+	b := img.Bounds()
+	step := 1 + int(math.Sqrt(float64(b.Dx()*b.Dy())/(1<<18))) // sample ~256K pixels
+	for y := b.Min.Y; y < b.Max.Y; y += step {
+		row := (y - b.Min.Y) * rows / b.Dy()
+		for x := b.Min.X; x < b.Max.X; x += step {
+			col := (x - b.Min.X) * cols / b.Dx()
+			sums[row][col] += float64(color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y)
+			counts[row][col]++
+		}
+	}
+
+	var hash uint64
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols-1; col++ {
+			left := sums[row][col] / math.Max(1, float64(counts[row][col]))
+			right := sums[row][col+1] / math.Max(1, float64(counts[row][col+1]))
+			hash <<= 1
+			if left > right {
+				hash |= 1
+			}
+		}
+	}
+
+	return hash
+}
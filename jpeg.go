@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// jpegSegment is a marker segment in the header of a JPEG file.
+type jpegSegment struct {
+	marker byte
+	data   []byte
+}
+
+// parseJPEGSegments returns the marker segments of the JPEG `content` up to the
+// start of the scan data.
+func parseJPEGSegments(content []byte) ([]jpegSegment, error) {
+	if len(content) < 2 || content[0] != 0xFF || content[1] != 0xD8 {
+		return nil, fmt.Errorf("missing JPEG SOI marker")
+	}
+
+	segments := make([]jpegSegment, 0)
+	for i := 2; i+4 <= len(content); {
+		if content[i] != 0xFF {
+			return nil, fmt.Errorf("invalid JPEG marker at offset %d", i)
+		}
+
+		marker := content[i+1]
+		if marker == 0xFF { // fill byte
+			i++
+			continue
+		}
+
+		length := int(binary.BigEndian.Uint16(content[i+2:]))
+		if length < 2 || i+2+length > len(content) {
+			return nil, fmt.Errorf("invalid JPEG segment length at offset %d", i)
+		}
+
+		segments = append(segments, jpegSegment{marker: marker, data: content[i+4 : i+2+length]})
+		if marker == 0xDA { // start of scan
+			break
+		}
+
+		i += 2 + length
+	}
+
+	return segments, nil
+}
+
+// stdLuminanceQuantTable is the luminance quantization table from the JPEG
+// standard (Annex K) that encoders scale according to the quality setting.
+var stdLuminanceQuantTable = [64]int{
+	16, 11, 10, 16, 24, 40, 51, 61,
+	12, 12, 14, 19, 26, 58, 60, 55,
+	14, 13, 16, 24, 40, 57, 69, 56,
+	14, 17, 22, 29, 51, 87, 80, 62,
+	18, 22, 37, 56, 68, 109, 103, 77,
+	24, 35, 55, 64, 81, 104, 113, 92,
+	49, 64, 78, 87, 103, 121, 120, 101,
+	72, 92, 95, 98, 112, 100, 103, 99,
+}
+
+// estimateJPEGQuality estimates the IJG quality setting (1-100) used to encode
+// a JPEG by comparing its luminance quantization table with the standard one.
+// It returns 0 if the file doesn't have a luminance quantization table.
+func estimateJPEGQuality(segments []jpegSegment) int {
+	for _, s := range segments {
+		if s.marker != 0xDB {
+			continue
+		}
+
+		for d := s.data; len(d) > 0; {
+			precision, id := d[0]>>4, d[0]&0x0F
+			size := 64
+			if precision != 0 {
+				size = 128
+			}
+
+			if len(d) < 1+size {
+				break
+			}
+
+			if id == 0 {
+				sum, stdSum := 0, 0
+				for i := 0; i < 64; i++ {
+					if precision != 0 {
+						sum += int(binary.BigEndian.Uint16(d[1+2*i:]))
+					} else {
+						sum += int(d[1+i])
+					}
+
+					stdSum += stdLuminanceQuantTable[i]
+				}
+
+				// IJG encoders scale the standard table by `scale` percent.
+				scale := float64(sum) * 100 / float64(stdSum)
+				quality := 5000 / scale
+				if scale <= 100 {
+					quality = (200 - scale) / 2
+				}
+
+				if quality < 1 {
+					return 1
+				} else if quality > 100 {
+					return 100
+				}
+
+				return int(quality + 0.5)
+			}
+
+			d = d[1+size:]
+		}
+	}
+
+	return 0
+}
+
+// checkJPEGQuality warns if the estimated quality of the JPEG image is below
+// `minJPEGQuality`, which usually means visible compression artifacts.
+func checkJPEGQuality(filePath string, config *imageConfig) []error {
+	if minJPEGQuality <= 0 || config.segmentsErr != nil {
+		return nil // reported by checkJPEGColorModel
+	}
+
+	if quality := estimateJPEGQuality(config.segments); quality > 0 && quality < minJPEGQuality {
+		const errFmt = "JPEG seems over-compressed: expected quality>=%d, got=~%d"
+		return []error{&validationError{
+			File:     filePath,
+			Rule:     "jpeg-quality",
+			Err:      fmt.Errorf(errFmt, minJPEGQuality, quality),
+			Severity: severityWarning,
+		}}
+	}
+
+	return nil
+}
+
+// jpegColorModel returns the color model of the JPEG from the number of the
+// components in its frame header and its Adobe APP14 segment, if any.
+func jpegColorModel(segments []jpegSegment) string {
+	transform := -1
+	for _, s := range segments {
+		if s.marker == 0xEE && len(s.data) >= 12 && string(s.data[:5]) == "Adobe" {
+			transform = int(s.data[11])
+		}
+	}
+
+	for _, s := range segments {
+		// SOF0-SOF15, except DHT (0xC4), JPG (0xC8) and DAC (0xCC)
+		if s.marker < 0xC0 || s.marker > 0xCF || s.marker == 0xC4 || s.marker == 0xC8 || s.marker == 0xCC || len(s.data) < 6 {
+			continue
+		}
+
+		switch s.data[5] {
+		case 1:
+			return "grayscale"
+		case 3:
+			if transform == 0 {
+				return "RGB"
+			}
+
+			return "YCbCr"
+		case 4:
+			if transform == 2 {
+				return "YCCK"
+			}
+
+			return "CMYK"
+		}
+
+		return fmt.Sprintf("%d-component", s.data[5])
+	}
+
+	return ""
+}
+
+// isProgressiveJPEG reports whether the frame header of the JPEG is one of the
+// progressive ones: SOF2, SOF6, SOF10 or SOF14.
+func isProgressiveJPEG(segments []jpegSegment) bool {
+	for _, s := range segments {
+		switch s.marker {
+		case 0xC2, 0xC6, 0xCA, 0xCE:
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkJPEGColorModel checks that the JPEG image is YCbCr or RGB encoded. Play
+// renders CMYK JPEGs, which some design tools export, with inverted colors. It
+// also reports the JPEGs whose segments can't be read, since it always runs.
+func checkJPEGColorModel(filePath string, config *imageConfig) []error {
+	if config.segmentsErr != nil {
+		return checkImageIntegrity(filePath, config.segmentsErr)
+	}
+
+	if model := jpegColorModel(config.segments); model != "" && model != "YCbCr" && model != "RGB" {
+		const errFmt = "JPEG must be YCbCr or RGB encoded: got %s, which Google Play may render with wrong colors"
+		return []error{&validationError{
+			File: filePath,
+			Rule: "jpeg-color-model",
+			Err:  fmt.Errorf(errFmt, model),
+		}}
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// languageStopwords declares the most frequent function words of languages
+// written in the Latin script, keyed by the language part of the locale. They
+// are enough to tell the language of a description apart, e.g. English text
+// copied into a German locale, without shipping a language model.
+var languageStopwords = map[string][]string{
+	"da": {"og", "er", "at", "det", "en", "et", "for", "med", "ikke", "som", "på", "af", "til", "du", "din", "dit", "kan", "den", "har", "eller", "også"},
+	"de": {"der", "die", "das", "und", "ist", "mit", "für", "den", "dem", "nicht", "sie", "ein", "eine", "auch", "auf", "sich", "von", "zu", "werden", "wird", "oder", "ihre", "kann", "alle", "sind", "noch", "wie", "mehr"},
+	"en": {"the", "and", "of", "to", "is", "with", "for", "your", "you", "this", "that", "are", "it", "on", "from", "can", "all", "be", "have", "will", "or", "an", "by", "more", "not", "what", "which"},
+	"es": {"el", "la", "los", "las", "y", "es", "una", "un", "para", "con", "que", "por", "del", "en", "su", "sus", "tu", "más", "como", "lo", "al", "está", "puedes", "también"},
+	"fr": {"le", "la", "les", "et", "est", "des", "une", "un", "pour", "dans", "avec", "vous", "votre", "qui", "que", "sur", "pas", "plus", "ce", "sont", "au", "aux", "par", "du", "ou", "être"},
+	"id": {"dan", "yang", "di", "ini", "untuk", "dengan", "dari", "anda", "tidak", "dalam", "akan", "ke", "bisa", "juga", "atau", "lebih"},
+	"it": {"il", "lo", "la", "gli", "le", "e", "è", "di", "una", "un", "per", "con", "che", "non", "sono", "del", "della", "dei", "più", "anche", "tuo", "tua", "puoi", "questo", "nel"},
+	"nl": {"de", "het", "een", "en", "is", "van", "voor", "met", "niet", "dat", "die", "je", "jouw", "uw", "op", "te", "zijn", "wordt", "ook", "kan", "naar", "bij", "meer"},
+	"no": {"og", "er", "at", "det", "en", "et", "for", "med", "ikke", "som", "på", "av", "til", "du", "din", "ditt", "kan", "den", "har", "eller", "også"},
+	"pl": {"i", "w", "z", "na", "się", "nie", "jest", "do", "że", "to", "jak", "dla", "oraz", "lub", "jego", "twój", "możesz", "aby", "po", "przez"},
+	"pt": {"o", "a", "os", "as", "e", "é", "de", "uma", "um", "para", "com", "que", "não", "do", "da", "dos", "das", "em", "seu", "sua", "você", "mais", "pode", "também"},
+	"sv": {"och", "är", "att", "det", "en", "ett", "för", "med", "inte", "som", "på", "av", "till", "du", "din", "ditt", "kan", "den", "har", "eller", "också"},
+	"tr": {"ve", "bir", "bu", "için", "ile", "de", "da", "çok", "daha", "olan", "gibi", "her", "en", "sizin", "siz", "olarak", "veya"},
+}
+
+// minLanguageCheckWords is the minimum number of words a text needs for the
+// language detection to be meaningful.
+const minLanguageCheckWords = 20
+
+// checkLocaleLanguage warns if the descriptions of a locale are detected to be
+// in another language with a confidence of at least `languageConfidence`, e.g.
+// when a translator overwrote the wrong file.
+func checkLocaleLanguage(localePath string) []error {
+	locale := filepath.Base(localePath)
+	language := strings.SplitN(locale, "-", 2)[0]
+	if _, ok := languageStopwords[language]; !ok || languageConfidence <= 0 {
+		return nil
+	}
+
+	errs := make([]error, 0)
+	for _, file := range []string{"short_description.txt", "full_description.txt"} {
+		filePath := layout.textPath(localePath, file)
+		content, err := readFile(filePath)
+		if err != nil {
+			continue // already reported by checkDescriptiveTexts
+		}
+
+		detected, confidence := detectLanguage(string(content))
+		if detected != "" && detected != language && confidence >= languageConfidence {
+			const errFmt = "text seems to be in %q rather than %q: %.0f%% confidence"
+			errs = append(errs, &validationError{
+				File:     filePath,
+				Rule:     "locale-language",
+				Err:      fmt.Errorf(errFmt, detected, language, confidence*100),
+				Severity: severityWarning,
+			})
+		}
+	}
+
+	return errs
+}
+
+// detectLanguage returns the language whose stopwords occur the most in `text`
+// and the share of all stopword occurrences that belong to it. Stopwords shared
+// by several languages count towards each, so that closely related languages
+// lower the confidence rather than being mistaken for one another.
+func detectLanguage(text string) (string, float64) {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) })
+	if len(words) < minLanguageCheckWords {
+		return "", 0
+	}
+
+	counts := make(map[string]int)
+	total := 0
+	for _, w := range words {
+		for language, stopwords := range languageStopwords {
+			if containsString(stopwords, w) {
+				counts[language]++
+				total++
+			}
+		}
+	}
+
+	detected := ""
+	for language, count := range counts {
+		if count > counts[detected] || count == counts[detected] && language < detected {
+			detected = language
+		}
+	}
+
+	if total == 0 {
+		return "", 0
+	}
+
+	return detected, float64(counts[detected]) / float64(total)
+}
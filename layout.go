@@ -0,0 +1,140 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// metadataLayout describes where a directory layout keeps the metadata of a
+// locale. Checks refer to files by their fastlane names, e.g.
+// `short_description.txt` or `featureGraphic`, and the layout maps them to
+// the paths it uses.
+type metadataLayout struct {
+	listingsDir string            // directory containing the locales; empty for the root
+	textFiles   map[string]string // fastlane text file names to the layout's names
+	imagesDir   string
+	imageDirs   map[string]string // graphic directories to fastlane image names; nil if images are files
+	// releaseNotesDir contains a directory of release notes for each locale if
+	// set. Otherwise, the release notes are in the `changelogs` directory of the
+	// locale and are named after version codes.
+	releaseNotesDir string
+}
+
+// layouts contains the supported metadata layouts by their `-layout` names.
+var layouts = map[string]*metadataLayout{
+	"fastlane": {
+		imagesDir: "images",
+	},
+	// Gradle Play Publisher, i.e. `src/<flavor>/play`.
+	"gpp": {
+		listingsDir: "listings",
+		textFiles: map[string]string{
+			"short_description.txt": "short-description.txt",
+			"full_description.txt":  "full-description.txt",
+			"video.txt":             "video-url.txt",
+		},
+		imagesDir: "graphics",
+		imageDirs: map[string]string{
+			"icon":                     "icon",
+			"feature-graphic":          "featureGraphic",
+			"promo-graphic":            "promoGraphic",
+			"tv-banner":                "tvBanner",
+			"phone-screenshots":        "phoneScreenshots",
+			"tablet-screenshots":       "sevenInchScreenshots",
+			"large-tablet-screenshots": "tenInchScreenshots",
+			"tv-screenshots":           "tvScreenshots",
+			"wear-screenshots":         "wearScreenshots",
+		},
+		releaseNotesDir: "release-notes",
+	},
+}
+
+// layout is the metadata layout selected by `-layout`.
+var layout = layouts["fastlane"]
+
+// textPath returns the path of the text file with the fastlane `name` in the
+// locale at `localePath`.
+func (l *metadataLayout) textPath(localePath, name string) string {
+	if n, ok := l.textFiles[name]; ok {
+		name = n
+	}
+
+	return filepath.Join(localePath, name)
+}
+
+// imageName returns the fastlane name of the image or the screenshot directory
+// named `name` in the images directory.
+func (l *metadataLayout) imageName(name string) string {
+	if n, ok := l.imageDirs[name]; ok {
+		return n
+	}
+
+	return name
+}
+
+// imagePath returns the path of the screenshot directory or, if images are
+// kept in directories, the image directory with the fastlane `name` in
+// `imagesPath`.
+func (l *metadataLayout) imagePath(imagesPath, name string) string {
+	for dir, n := range l.imageDirs {
+		if n == name {
+			return filepath.Join(imagesPath, dir)
+		}
+	}
+
+	return filepath.Join(imagesPath, name)
+}
+
+// hasImage reports whether `imagesPath` contains the image with the fastlane
+// `name`.
+func (l *metadataLayout) hasImage(imagesPath, name string) bool {
+	if l.imageDirs != nil {
+		files, _ := readDir(l.imagePath(imagesPath, name))
+		for _, f := range files {
+			if !f.IsDir() {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	files, _ := readDir(imagesPath)
+	for _, f := range files {
+		if !f.IsDir() && strings.TrimSuffix(f.Name(), filepath.Ext(f.Name())) == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// changelogsPath returns the path of the directory containing the release
+// notes of the locale at `localePath`.
+func (l *metadataLayout) changelogsPath(localePath string) string {
+	if l.releaseNotesDir == "" {
+		return filepath.Join(localePath, "changelogs")
+	}
+
+	rootPath := filepath.Dir(filepath.Dir(localePath))
+	return filepath.Join(rootPath, l.releaseNotesDir, filepath.Base(localePath))
+}
+
+// localePath returns the path of the locale directory that the text file at
+// `filePath` belongs to.
+func (l *metadataLayout) localePath(filePath string) string {
+	dir := filepath.Dir(filePath)
+	if l.releaseNotesDir == "" {
+		if filepath.Base(dir) == "changelogs" {
+			return filepath.Dir(dir)
+		}
+
+		return dir
+	}
+
+	if rootPath := filepath.Dir(filepath.Dir(dir)); filepath.Base(filepath.Dir(dir)) == l.releaseNotesDir {
+		return filepath.Join(rootPath, l.listingsDir, filepath.Base(dir))
+	}
+
+	return dir
+}
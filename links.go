@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+var linkRegexp = regexp.MustCompile(`(?i)\bhttps?://[^\s<>"'\x60]+`)
+
+// linkCacheTTL is how long the successful results in the `-link-cache` file
+// are reused. Broken links are checked again on every run.
+const linkCacheTTL = 24 * time.Hour
+
+// linkResult is the result of resolving a URL.
+type linkResult struct {
+	Status    int       `json:"status"`
+	Err       string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+func (r linkResult) isBroken() bool { return r.Err != "" || r.Status >= 400 }
+
+// linkResults caches the results of the URLs resolved in this run, and those
+// loaded from the `-link-cache` file.
+var linkResults = make(map[string]linkResult)
+
+// extractLinks returns the URLs in `text`, without the trailing punctuation
+// that usually ends the sentence around them.
+func extractLinks(text string) []string {
+	links := make([]string, 0)
+	for _, link := range linkRegexp.FindAllString(text, -1) {
+		link = strings.TrimRight(link, ".,;:!?*")
+		if strings.Count(link, ")") > strings.Count(link, "(") {
+			link = strings.TrimRight(link, ")")
+		}
+
+		links = append(links, link)
+	}
+
+	return links
+}
+
+// checkLinks checks that the URLs in the descriptive texts of the locale are
+// well-formed and, with `-check-links`, that they resolved in `resolveLinks`.
+func checkLinks(localePath string) []error {
+	errs := make([]error, 0)
+	for _, file := range descriptiveFiles {
+		filePath := layout.textPath(localePath, file)
+		content, err := readFile(filePath)
+		if err != nil {
+			continue // already reported by checkDescriptiveTexts
+		}
+
+		for _, link := range extractLinks(string(content)) {
+			if u, err := url.Parse(link); err != nil || u.Hostname() == "" {
+				const errFmt = "malformed URL %q"
+				errs = append(errs, &validationError{
+					File: filePath,
+					Rule: "link",
+					Err:  fmt.Errorf(errFmt, link),
+				})
+				continue
+			}
+
+			if r, ok := linkResults[link]; ok && r.isBroken() {
+				const errFmt = "URL %q is broken: %s"
+				reason := r.Err
+				if reason == "" {
+					reason = fmt.Sprintf("HTTP status %d", r.Status)
+				}
+
+				errs = append(errs, &validationError{
+					File: filePath,
+					Rule: "link",
+					Err:  fmt.Errorf(errFmt, link, reason),
+				})
+			}
+		}
+	}
+
+	return errs
+}
+
+// resolveLinks resolves the URLs in the descriptive texts of all locales in
+// the trees with at most `linkConcurrency` concurrent requests, so that
+// checkLinks can report the broken ones. Each URL is only requested once, even
+// if many locales share it.
+func resolveLinks(trees []localeTree) error {
+	if linkCachePath != "" {
+		if err := loadLinkCache(linkCachePath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	links := make(map[string]bool)
+	for _, t := range trees {
+		for _, locale := range t.locales {
+			for _, file := range descriptiveFiles {
+				content, err := readFile(layout.textPath(filepath.Join(t.path, locale), file))
+				if err != nil {
+					continue
+				}
+
+				for _, link := range extractLinks(string(content)) {
+					if r, ok := linkResults[link]; !ok || r.isBroken() || time.Since(r.CheckedAt) > linkCacheTTL {
+						links[link] = true
+					}
+				}
+			}
+		}
+	}
+
+	client := &http.Client{Timeout: linkTimeout}
+	sem := make(chan struct{}, linkConcurrency)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for link := range links {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(link string) {
+			defer func() { <-sem; wg.Done() }()
+			r := resolveLink(client, link)
+			mu.Lock()
+			linkResults[link] = r
+			mu.Unlock()
+		}(link)
+	}
+
+	wg.Wait()
+	if linkCachePath != "" {
+		return saveLinkCache(linkCachePath)
+	}
+
+	return nil
+}
+
+// resolveLink requests `link` with a HEAD request, falling back to GET since
+// some servers reject HEAD requests.
+func resolveLink(client *http.Client, link string) linkResult {
+	r := linkResult{CheckedAt: time.Now().UTC()}
+	for _, method := range []string{http.MethodHead, http.MethodGet} {
+		req, err := http.NewRequest(method, link, nil)
+		if err != nil {
+			r.Err = err.Error()
+			return r
+		}
+
+		req.Header.Set("User-Agent", "validate-fastlane-supply-metadata")
+		resp, err := client.Do(req)
+		if err != nil {
+			r.Status, r.Err = 0, err.Error()
+			continue
+		}
+
+		resp.Body.Close()
+		r.Status, r.Err = resp.StatusCode, ""
+		if !r.isBroken() {
+			break
+		}
+	}
+
+	return r
+}
+
+// loadLinkCache reads the results of the previous runs from the cache file at
+// `path`.
+func loadLinkCache(path string) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(content, &linkResults); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	return nil
+}
+
+// saveLinkCache writes the successful results to the cache file at `path`.
+func saveLinkCache(path string) error {
+	cache := make(map[string]linkResult, len(linkResults))
+	for link, r := range linkResults {
+		if !r.isBroken() {
+			cache[link] = r
+		}
+	}
+
+	content, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, append(content, '\n'), 0o644)
+}
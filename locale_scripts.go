@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// localeScripts declares the scripts expected in the text of languages that
+// aren't written in the Latin script, keyed by the language part of the locale.
+var localeScripts = map[string][]*unicode.RangeTable{
+	"am": {unicode.Ethiopic},
+	"ar": {unicode.Arabic},
+	"be": {unicode.Cyrillic},
+	"bg": {unicode.Cyrillic},
+	"bn": {unicode.Bengali},
+	"el": {unicode.Greek},
+	"fa": {unicode.Arabic},
+	"gu": {unicode.Gujarati},
+	"hi": {unicode.Devanagari},
+	"hy": {unicode.Armenian},
+	"iw": {unicode.Hebrew},
+	"ja": {unicode.Han, unicode.Hiragana, unicode.Katakana},
+	"ka": {unicode.Georgian},
+	"kk": {unicode.Cyrillic},
+	"km": {unicode.Khmer},
+	"kn": {unicode.Kannada},
+	"ko": {unicode.Hangul, unicode.Han},
+	"ky": {unicode.Cyrillic},
+	"lo": {unicode.Lao},
+	"mk": {unicode.Cyrillic},
+	"ml": {unicode.Malayalam},
+	"mn": {unicode.Cyrillic},
+	"mr": {unicode.Devanagari},
+	"my": {unicode.Myanmar},
+	"ne": {unicode.Devanagari},
+	"pa": {unicode.Gurmukhi},
+	"ru": {unicode.Cyrillic},
+	"si": {unicode.Sinhala},
+	"sr": {unicode.Cyrillic},
+	"ta": {unicode.Tamil},
+	"te": {unicode.Telugu},
+	"th": {unicode.Thai},
+	"uk": {unicode.Cyrillic},
+	"ur": {unicode.Arabic},
+	"zh": {unicode.Han},
+}
+
+// minScriptCheckLetters is the minimum number of letters a text needs for the
+// script check to be meaningful.
+const minScriptCheckLetters = 20
+
+// checkLocaleScript warns if the descriptions of a locale written in a
+// non-Latin script are overwhelmingly in the Latin script, which is a strong
+// signal of untranslated text.
+func checkLocaleScript(localePath string) []error {
+	locale := filepath.Base(localePath)
+	scripts, ok := localeScripts[strings.SplitN(locale, "-", 2)[0]]
+	if !ok || scriptMismatch <= 0 {
+		return nil
+	}
+
+	errs := make([]error, 0)
+	for _, file := range []string{"short_description.txt", "full_description.txt"} {
+		filePath := layout.textPath(localePath, file)
+		content, err := readFile(filePath)
+		if err != nil {
+			continue // already reported by checkDescriptiveTexts
+		}
+
+		letters, latin := 0, 0
+		for _, r := range string(content) {
+			if !unicode.IsLetter(r) {
+				continue
+			}
+
+			letters++
+			if unicode.Is(unicode.Latin, r) && !unicode.In(r, scripts...) {
+				latin++
+			}
+		}
+
+		if letters < minScriptCheckLetters {
+			continue
+		}
+
+		if ratio := float64(latin) / float64(letters); ratio >= scriptMismatch {
+			const errFmt = "%.0f%% of the text is in the Latin script: is it translated to %q?"
+			errs = append(errs, &validationError{
+				File:     filePath,
+				Rule:     "locale-script",
+				Err:      fmt.Errorf(errFmt, ratio*100, locale),
+				Severity: severityWarning,
+			})
+		}
+	}
+
+	return errs
+}
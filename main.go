@@ -1,15 +1,23 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"flag"
 	"fmt"
 	"image"
 	"io/ioutil"
 	"math"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
-	"unicode/utf8"
+	"sync"
+	"time"
 
 	_ "image/jpeg"
 	_ "image/png"
@@ -20,128 +28,1082 @@ type imageConfig struct {
 	height int
 	opaque bool
 	format string
+	// orientation is the EXIF orientation (1-8). The width and the height are
+	// those of the image as displayed, i.e. swapped for orientations 5-8.
+	orientation int
+	// content is the content of the image file, which getImageConfig reads
+	// once for all the checks of the image.
+	content []byte
+	// chunks are the PNG chunks preceding the image data, for PNGs.
+	chunks []pngChunk
+	// segments are the JPEG marker segments preceding the scan data, for
+	// JPEGs, and segmentsErr is the error of parsing them.
+	segments    []jpegSegment
+	segmentsErr error
+	// decoded and decodeErr are the result of decoding the image, if
+	// isDecoded.
+	decoded   image.Image
+	decodeErr error
+	isDecoded bool
 }
 
+// decode returns the decoded image, decoding it at most once, since decoding is
+// the slowest part of validating images.
+func (c *imageConfig) decode() (image.Image, error) {
+	if !c.isDecoded {
+		c.decoded, _, c.decodeErr = image.Decode(bytes.NewReader(c.content))
+		c.isDecoded = true
+	}
+
+	return c.decoded, c.decodeErr
+}
+
+// severity declares how serious a validation error is. Only errors with
+// `severityError` cause a non-zero exit status.
+type severity int
+
+const (
+	severityError severity = iota
+	severityWarning
+)
+
 type validationError struct {
-	File string
-	Err  error
+	File     string
+	Rule     string
+	Err      error
+	Severity severity
 }
 
 var _ error = &validationError{}
 
 func (e *validationError) Error() string {
+	if e.Severity == severityWarning {
+		return fmt.Sprintf("%s: warning: %s", e.File, e.Err.Error())
+	}
+
 	return fmt.Sprintf("%s: %s", e.File, e.Err.Error())
 }
 
 func (e *validationError) annotateGitHubFile() {
-	const errAnnotationFmt = "::error file=%s::%s\n"
+	const annotationFmt = "::%s file=%s::%s\n"
 	v := strings.ReplaceAll(e.Err.Error(), "%", "%25")
 	v = strings.ReplaceAll(v, "\r", "%0D")
 	v = strings.ReplaceAll(v, "\n", "%0A")
 
-	fmt.Printf(errAnnotationFmt, e.File, v)
+	level := "error"
+	if e.Severity == severityWarning {
+		level = "warning"
+	}
+
+	fmt.Printf(annotationFmt, level, e.File, v)
+}
+
+// pathList is a `flag.Value` collecting the paths given by repeating a flag or
+// separating them with commas. The first `Set` call replaces the default paths.
+type pathList struct {
+	paths []string
+	isSet bool
+}
+
+func (l *pathList) String() string {
+	if l == nil {
+		return ""
+	}
+
+	return strings.Join(l.paths, ",")
+}
+
+func (l *pathList) Set(value string) error {
+	if !l.isSet {
+		l.paths, l.isSet = nil, true
+	}
+
+	for _, p := range strings.Split(value, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			l.paths = append(l.paths, p)
+		}
+	}
+
+	return nil
+}
+
+// lengthList is a `flag.Value` collecting `name=length` pairs given by
+// repeating a flag or separating them with commas.
+type lengthList map[string]int
+
+func (l lengthList) String() string {
+	pairs := make([]string, 0, len(l))
+	for name, length := range l {
+		pairs = append(pairs, fmt.Sprintf("%s=%d", name, length))
+	}
+
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (l lengthList) Set(value string) error {
+	for _, pair := range strings.Split(value, ",") {
+		name, length := "", 0
+		parts := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(parts) == 2 {
+			name = strings.TrimSpace(parts[0])
+			length, _ = strconv.Atoi(strings.TrimSpace(parts[1]))
+		}
+
+		if name == "" || length <= 0 {
+			return fmt.Errorf("expected name=length with a positive length, got %q", pair)
+		}
+
+		l[name] = length
+	}
+
+	return nil
 }
 
 var (
-	fastlanePath        string
-	useFileAnnotations  bool
-	usePlayStoreLocales bool
+	configPath                 string
+	fastlanePaths              pathList
+	useFileAnnotations         bool
+	usePlayStoreLocales        bool
+	defaultLocale              string
+	versionCode                int
+	minChangelogLength         int
+	boilerplatePattern         string
+	boilerplateRunSize         int
+	titleAllowedSymbols        string
+	descriptionOverlap         float64
+	scriptMismatch             float64
+	keywordDensity             float64
+	languageConfidence         float64
+	iconPaddingThreshold       float64
+	letterboxThreshold         float64
+	duplicateDistance          int
+	framefilePath              string
+	minJPEGQuality             int
+	warnInterlaced             bool
+	deepImageCheck             bool
+	requireSRGB                bool
+	maxImagePixels             int
+	maxImageBytes              int64
+	iconAlpha                  string
+	screenshotNamePattern      string
+	outputFormat               string
+	jobs                       int
+	historyPath                string
+	trackedOnly                bool
+	useGitignore               bool
+	target                     string
+	minLocales                 int
+	shard                      string
+	shardIndex                 int
+	shardCount                 int
+	flavorMode                 string
+	profileName                string
+	layoutName                 string
+	gitRef                     string
+	gitDir                     string
+	changedSince               string
+	excludePatterns            pathList
+	symlinkPolicy              string
+	strictStructure            bool
+	videoRequireHTTPS          bool
+	fixWhitespace              bool
+	lineEndings                string
+	allowUntranslated          pathList
+	countMode                  string
+	requiredChangelog          int
+	requireChangelogFor        string
+	versionCodeFile            string
+	requireChangelogEverywhere bool
+	staleChangelogsKeep        int
+	staleChangelogsDays        int
+	maxChangelogs              int
+	maxLengths                 = lengthList{}
+	checkLinkTargets           bool
+	offline                    bool
+	linkTimeout                time.Duration
+	linkConcurrency            int
+	linkCachePath              string
+	skipMinLength              pathList
+	discover                   bool
+	useStdin                   bool
+	useFileList                bool
+	stdinFilename              string
+	colorMode                  string
 )
 
 func init() {
-	flag.StringVar(&fastlanePath, "fastlane-path", "./fastlane/metadata/android", "path to the Fastlane Android metadata directory")
+	flag.StringVar(&configPath, "config", "", "path to the JSON config file")
+	fastlanePaths = pathList{paths: []string{"./fastlane/metadata/android"}}
+	flag.Var(&fastlanePaths, "fastlane-path", "path to the Fastlane Android metadata directory; repeat or separate with commas to validate several")
 	flag.BoolVar(&useFileAnnotations, "ga-file-annotations", false, "enables file annotations for GitHub action")
 	flag.BoolVar(&usePlayStoreLocales, "play-store-locales", false, "throw an error if a locale isn't recognised by Google Play")
+	flag.StringVar(&defaultLocale, "default-locale", "en-US", "default locale of the Play Store listing; empty disables the checks that require it")
+	flag.IntVar(&versionCode, "version-code", 0, "version code of the release; defaults to the latest changelog in the default locale")
+	flag.StringVar(&requireChangelogFor, "require-changelog-for", "", "throw an error if the default locale has no changelog for this version code; auto reads it from the Gradle build")
+	flag.StringVar(&versionCodeFile, "version-code-file", "", "build.gradle(.kts), output-metadata.json or text file to read the version code for -require-changelog-for auto from")
+	flag.BoolVar(&requireChangelogEverywhere, "require-changelog-all-locales", false, "make -require-changelog-for apply to all locales")
+	flag.IntVar(&staleChangelogsKeep, "stale-changelogs-keep", 0, "warn about the changelogs older than this many of the highest version codes; 0 disables the check")
+	flag.IntVar(&staleChangelogsDays, "stale-changelogs-days", 0, "warn about the changelogs last committed more than this many days ago; 0 disables the check")
+	flag.IntVar(&maxChangelogs, "max-changelogs", 0, "warn if a locale has more changelog files than this; 0 disables the check")
+	flag.IntVar(&minChangelogLength, "min-changelog-length", 1, "warn if the default locale changelog for the release is shorter than this")
+	flag.StringVar(&boilerplatePattern, "boilerplate-changelog-pattern", "", "only consider repeated changelogs matching this regular expression as boilerplate")
+	flag.IntVar(&boilerplateRunSize, "boilerplate-changelog-threshold", 3, "warn if this many consecutive changelogs share the same text; 0 disables the check")
+	flag.StringVar(&titleAllowedSymbols, "title-allowed-symbols", "", "trademark or decorative symbols allowed in the title, e.g. \"®™\"")
+	flag.Float64Var(&descriptionOverlap, "description-overlap-threshold", 0.8, "warn if this fraction of the short description is copied from the full description; 0 disables the check")
+	flag.Float64Var(&scriptMismatch, "script-mismatch-threshold", 0.9, "warn if this fraction of a non-Latin locale's description is in the Latin script; 0 disables the check")
+	flag.Var(&allowUntranslated, "allow-untranslated", "glob pattern of the locales, e.g. en-*, allowed to share descriptions and changelogs with the default locale; repeatable")
+	flag.Float64Var(&languageConfidence, "language-confidence-threshold", 0.7, "warn if a description is detected to be in another language than its locale with this confidence; 0 disables the check")
+	flag.Float64Var(&keywordDensity, "keyword-density-threshold", 0.05, "warn if a word makes up more than this fraction of the full description; 0 disables the check")
+	flag.Float64Var(&iconPaddingThreshold, "icon-padding-threshold", 0.3, "warn if this fraction of the icon is a transparent border; 0 disables the check")
+	flag.Float64Var(&letterboxThreshold, "letterbox-threshold", 0.1, "warn if uniform bars make up this fraction of a screenshot's width or height; 0 disables the check")
+	flag.IntVar(&duplicateDistance, "duplicate-screenshot-distance", 4, "warn about screenshots whose perceptual hashes differ in at most this many of 64 bits; -1 only reports identical files")
+	flag.StringVar(&framefilePath, "framefile", "./fastlane/screenshots/Framefile.json", "path to the frameit config; framed screenshots are skipped if it exists")
+	flag.IntVar(&minJPEGQuality, "min-jpeg-quality", 50, "warn if the estimated quality of a JPEG image is below this; 0 disables the check")
+	flag.StringVar(&iconAlpha, "icon-alpha", "allow", "alpha channel policy of the icon: allow, require-opaque or require-alpha")
+	flag.StringVar(&screenshotNamePattern, "screenshot-name-pattern", "", "throw an error if a screenshot's file name doesn't match this regular expression, e.g. ^\\d{2}_[a-z]+\\.png$")
+	flag.IntVar(&maxImagePixels, "max-image-pixels", 50_000_000, "refuse to decode images with more pixels than this, e.g. decompression bombs")
+	flag.Int64Var(&maxImageBytes, "max-image-bytes", 100<<20, "refuse to read images larger than this many bytes")
+	flag.BoolVar(&requireSRGB, "require-srgb", false, "warn about images with a color profile other than sRGB, e.g. Display P3 or Adobe RGB")
+	flag.BoolVar(&warnInterlaced, "warn-interlaced", false, "warn about interlaced PNGs and progressive JPEGs, which Google Play may fail to process")
+	flag.BoolVar(&deepImageCheck, "deep-image-check", false, "fully decode the images to catch truncated and corrupt files, which reading their headers misses")
+	flag.Var(maxLengths, "max-length", "maximum length of a text file, e.g. title.txt=50, or of changelogs, e.g. changelogs=500; repeatable and overrides the config")
+	flag.StringVar(&countMode, "count-mode", "grapheme", "how to count the length of texts: grapheme (user-perceived characters, like Play Console) or rune")
+	flag.Var(&skipMinLength, "skip-min-length", "descriptive text file, e.g. title.txt, to skip the minimum length check for; repeatable")
+	flag.BoolVar(&videoRequireHTTPS, "video-require-https", false, "throw an error if the promo video URL doesn't use HTTPS")
+	flag.BoolVar(&checkLinkTargets, "check-links", false, "throw an error if a URL in the descriptive texts doesn't resolve")
+	flag.BoolVar(&offline, "offline", false, "never access the network; overrides -check-links")
+	flag.DurationVar(&linkTimeout, "link-timeout", 10*time.Second, "timeout of each request made by -check-links")
+	flag.IntVar(&linkConcurrency, "link-concurrency", 4, "maximum number of concurrent requests made by -check-links")
+	flag.StringVar(&linkCachePath, "link-cache", "", "reuse the URLs that -check-links resolved in the last 24 hours from this cache file")
+	flag.BoolVar(&fixWhitespace, "fix", false, "normalise the whitespace and the line endings of text files in place instead of reporting them")
+	flag.StringVar(&lineEndings, "line-endings", "lf", "line endings of text files: lf, crlf, consistent or any")
+	flag.BoolVar(&strictStructure, "strict", false, "throw an error for files and directories that supply doesn't use")
+	flag.StringVar(&outputFormat, "format", "text", "output format: text or json")
+	flag.IntVar(&jobs, "j", runtime.GOMAXPROCS(0), "number of locales to validate concurrently")
+	flag.StringVar(&historyPath, "history", "", "append a summary of the run to this history file")
+	flag.BoolVar(&trackedOnly, "tracked-only", false, "skip files that aren't tracked by git")
+	flag.StringVar(&changedSince, "changed-since", "", "only validate the locales with files changed since this git ref")
+	flag.Var(&excludePatterns, "exclude", "skip files matching this gitignore-style pattern relative to the fastlane path; repeatable")
+	flag.StringVar(&symlinkPolicy, "symlinks", "follow", "how to handle symlinks: follow, skip or error")
+	flag.BoolVar(&useGitignore, "gitignore", false, "skip files ignored by .gitignore files")
+	flag.StringVar(&profileName, "profile", "play", "rule set of the target store: play or fdroid")
+	flag.StringVar(&target, "target", "", "name of the target store; selects the matching config rules")
+	flag.IntVar(&minLocales, "min-locales", 0, "throw an error if there are fewer complete locales than this; overrides the config")
+	flag.StringVar(&shard, "shard", "", "only validate the i-th of n shards of locales, e.g. 1/4")
+	flag.BoolVar(&useStdin, "stdin", false, "validate the content read from stdin as if it were the -stdin-filename file")
+	flag.BoolVar(&useFileList, "files-from-stdin", false, "only validate the metadata files listed on stdin, one per line")
+	flag.StringVar(&stdinFilename, "stdin-filename", "", "path of the text file that the content read from stdin replaces")
+	flag.StringVar(&layoutName, "layout", "fastlane", "metadata directory layout: fastlane or gpp (Gradle Play Publisher)")
+	flag.StringVar(&flavorMode, "flavors", "off", "validate product flavor subdirectories containing locales: off, on or auto")
+	flag.StringVar(&gitRef, "git-ref", "", "read the metadata at this git ref, e.g. origin/main, instead of the working tree")
+	flag.StringVar(&gitDir, "git-dir", "", "read -git-ref from this git directory, e.g. a bare repository, with the fastlane paths relative to its root")
+	flag.BoolVar(&discover, "discover", false, "validate every fastlane/metadata/android directory found in the working directory")
+	flag.StringVar(&colorMode, "color", "auto", "colorize the text output: auto, always or never")
+	flag.Usage = func() {
+		const usageFmt = "Usage: %s [flags] [command]\n\n" +
+			"Commands:\n" +
+			"  manifest\twrite a checksum manifest of the validated metadata files\n" +
+			"  compare\tprint new, fixed and persisting issues between two JSON reports\n" +
+			"  trend\tprint the run summaries and regressions in the history file\n" +
+			"  merge-reports\tcombine the JSON reports of several runs into one\n" +
+			"  locales\tprint the locales recognised by Google Play\n" +
+			"  matrix\tprint which metadata of the default locale the other locales are missing\n" +
+			"  changelog-gaps\tprint the changelog version codes that some locales are missing\n\n" +
+			"Flags:\n"
+		fmt.Fprintf(flag.CommandLine.Output(), usageFmt, os.Args[0])
+		flag.PrintDefaults()
+	}
+
 	flag.Parse()
 }
 
 func main() {
-	files, err := ioutil.ReadDir(fastlanePath)
+	start := time.Now()
+	if p, ok := profiles[profileName]; ok {
+		activeProfile = p
+		cfg = defaultConfig()
+	} else {
+		const errFmt = "invalid profile %q: expected play or fdroid\n"
+		fmt.Fprintf(os.Stderr, errFmt, profileName)
+		os.Exit(2)
+	}
+
+	if configPath != "" {
+		c, err := loadConfig(configPath)
+		if err != nil {
+			const errFmt = "failed to load config %q: %s\n"
+			fmt.Fprintf(os.Stderr, errFmt, configPath, err)
+			os.Exit(1)
+		}
+
+		cfg = c
+	}
+
+	if minLocales == 0 {
+		minLocales = cfg.MinLocales
+	}
+
+	if outputFormat != "text" && outputFormat != "json" {
+		const errFmt = "invalid output format %q: expected text or json\n"
+		fmt.Fprintf(os.Stderr, errFmt, outputFormat)
+		os.Exit(2)
+	}
+
+	if colorMode != "auto" && colorMode != "always" && colorMode != "never" {
+		const errFmt = "invalid color mode %q: expected auto, always or never\n"
+		fmt.Fprintf(os.Stderr, errFmt, colorMode)
+		os.Exit(2)
+	}
+
+	if flavorMode != "off" && flavorMode != "on" && flavorMode != "auto" {
+		const errFmt = "invalid flavors mode %q: expected off, on or auto\n"
+		fmt.Fprintf(os.Stderr, errFmt, flavorMode)
+		os.Exit(2)
+	}
+
+	if l, ok := layouts[layoutName]; ok {
+		layout = l
+	} else {
+		const errFmt = "invalid layout %q: expected fastlane or gpp\n"
+		fmt.Fprintf(os.Stderr, errFmt, layoutName)
+		os.Exit(2)
+	}
+
+	if symlinkPolicy != "follow" && symlinkPolicy != "skip" && symlinkPolicy != "error" {
+		const errFmt = "invalid symlink policy %q: expected follow, skip or error\n"
+		fmt.Fprintf(os.Stderr, errFmt, symlinkPolicy)
+		os.Exit(2)
+	}
+
+	if code, err := resolveRequiredChangelog(requireChangelogFor, versionCodeFile); err == nil {
+		requiredChangelog = code
+	} else {
+		const errFmt = "failed to determine the version code for -require-changelog-for: %s\n"
+		fmt.Fprintf(os.Stderr, errFmt, err)
+		os.Exit(2)
+	}
+
+	if requiredChangelog > 0 && layout.releaseNotesDir != "" {
+		const errFmt = "-require-changelog-for is only supported by the fastlane layout: release notes aren't named after version codes\n"
+		fmt.Fprint(os.Stderr, errFmt)
+		os.Exit(2)
+	}
+
+	if gitDir != "" && gitRef == "" {
+		const errFmt = "-git-dir requires -git-ref\n"
+		fmt.Fprint(os.Stderr, errFmt)
+		os.Exit(2)
+	}
+
+	if countMode != "grapheme" && countMode != "rune" {
+		const errFmt = "invalid count mode %q: expected grapheme or rune\n"
+		fmt.Fprintf(os.Stderr, errFmt, countMode)
+		os.Exit(2)
+	}
+
+	if iconAlpha != "allow" && iconAlpha != "require-opaque" && iconAlpha != "require-alpha" {
+		const errFmt = "invalid icon alpha policy %q: expected allow, require-opaque or require-alpha\n"
+		fmt.Fprintf(os.Stderr, errFmt, iconAlpha)
+		os.Exit(2)
+	}
+
+	if screenshotNamePattern != "" {
+		var err error
+		screenshotNameRegexp, err = regexp.Compile(screenshotNamePattern)
+		if err != nil {
+			const errFmt = "invalid screenshot name pattern %q: %s\n"
+			fmt.Fprintf(os.Stderr, errFmt, screenshotNamePattern, err)
+			os.Exit(2)
+		}
+	}
+
+	switch lineEndings {
+	case "lf", "consistent":
+	case "crlf":
+		expectedLineEnding = "\r\n"
+	case "any":
+		expectedLineEnding = ""
+	default:
+		const errFmt = "invalid line endings %q: expected lf, crlf, consistent or any\n"
+		fmt.Fprintf(os.Stderr, errFmt, lineEndings)
+		os.Exit(2)
+	}
+
+	if layoutName != "fastlane" && flavorMode != "off" {
+		const errFmt = "-flavors is only supported by the fastlane layout: pass the directory of each flavor with -fastlane-path instead\n"
+		fmt.Fprint(os.Stderr, errFmt)
+		os.Exit(2)
+	}
+
+	if shard != "" {
+		var err error
+		shardIndex, shardCount, err = parseShard(shard)
+		if err != nil {
+			const errFmt = "invalid shard %q: %s\n"
+			fmt.Fprintf(os.Stderr, errFmt, shard, err)
+			os.Exit(2)
+		}
+	}
+
+	if discover {
+		paths, err := discoverFastlanePaths(".")
+		if err == nil && len(paths) == 0 {
+			err = fmt.Errorf("no fastlane/metadata/android directories found")
+		}
+
+		if err != nil {
+			const errFmt = "failed to discover fastlane directories: %s\n"
+			fmt.Fprintf(os.Stderr, errFmt, err)
+			os.Exit(1)
+		}
+
+		fastlanePaths.paths = paths
+	}
+
+	for _, p := range fastlanePaths.paths {
+		if gitRef != "" {
+			if err := mountGitRef(p, gitRef); err != nil {
+				const errFmt = "failed to read %q at git ref %q: %s\n"
+				fmt.Fprintf(os.Stderr, errFmt, p, gitRef, err)
+				os.Exit(1)
+			}
+		} else if err := mountArchive(p); err != nil {
+			const errFmt = "failed to read archive %q: %s\n"
+			fmt.Fprintf(os.Stderr, errFmt, p, err)
+			os.Exit(1)
+		}
+	}
+
+	if trackedOnly {
+		trackedPaths = make(map[string]bool)
+		for _, p := range fastlanePaths.paths {
+			if _, _, ok := mountedPath(p); ok {
+				continue // archives aren't in git work trees
+			}
+
+			paths, err := loadTrackedPaths(p)
+			if err != nil {
+				const errFmt = "failed to list tracked files in %q: %s\n"
+				fmt.Fprintf(os.Stderr, errFmt, p, err)
+				os.Exit(1)
+			}
+
+			for tp := range paths {
+				trackedPaths[tp] = true
+			}
+		}
+	}
+
+	if useGitignore {
+		ignoredPaths = make([]*gitignore, 0, len(fastlanePaths.paths))
+		for _, p := range fastlanePaths.paths {
+			if _, _, ok := mountedPath(p); ok {
+				continue // archives aren't in git work trees
+			}
+
+			g, err := newGitignore(p)
+			if err != nil {
+				const errFmt = "failed to read .gitignore files for %q: %s\n"
+				fmt.Fprintf(os.Stderr, errFmt, p, err)
+				os.Exit(1)
+			}
+
+			ignoredPaths = append(ignoredPaths, g)
+		}
+	}
+
+	for _, p := range fastlanePaths.paths {
+		g, err := newValidateIgnore(p, excludePatterns.paths)
+		if err != nil {
+			const errFmt = "failed to read .validateignore files for %q: %s\n"
+			fmt.Fprintf(os.Stderr, errFmt, p, err)
+			os.Exit(1)
+		}
+
+		ignoredPaths = append(ignoredPaths, g)
+	}
+
+	switch flag.Arg(0) {
+	case "":
+	case "manifest":
+		manifestCommand(flag.Args()[1:])
+		return
+	case "compare":
+		compareCommand(flag.Args()[1:])
+		return
+	case "trend":
+		trendCommand(flag.Args()[1:])
+		return
+	case "merge-reports":
+		mergeReportsCommand(flag.Args()[1:])
+		return
+	case "locales":
+		localesCommand(flag.Args()[1:])
+		return
+	case "matrix":
+		matrixCommand(flag.Args()[1:])
+		return
+	case "changelog-gaps":
+		changelogGapsCommand(flag.Args()[1:])
+		return
+	default:
+		const errFmt = "unknown command %q\n"
+		fmt.Fprintf(os.Stderr, errFmt, flag.Arg(0))
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	boilerplateRegexp, err := regexp.Compile(boilerplatePattern)
+	if err != nil {
+		const errFmt = "invalid boilerplate changelog pattern %q: %s\n"
+		fmt.Fprintf(os.Stderr, errFmt, boilerplatePattern, err)
+		os.Exit(1)
+	}
+
+	if changedSince != "" {
+		changedPaths = make(map[string]bool)
+		for _, p := range fastlanePaths.paths {
+			if _, _, ok := mountedPath(p); ok {
+				continue // archives aren't in git work trees
+			}
+
+			paths, err := loadChangedPaths(p, changedSince)
+			if err != nil {
+				const errFmt = "failed to list files changed in %q: %s\n"
+				fmt.Fprintf(os.Stderr, errFmt, p, err)
+				os.Exit(1)
+			}
+
+			for cp := range paths {
+				changedPaths[cp] = true
+			}
+		}
+	}
+
+	if useStdin {
+		validateStdin(boilerplateRegexp, start)
+		return
+	}
+
+	trees := make([]localeTree, 0)
+	for _, p := range fastlanePaths.paths {
+		t, err := findLocaleTrees(p)
+		if err != nil {
+			const errFmt = "failed to read directory %q: %s\n"
+			fmt.Fprintf(os.Stderr, errFmt, p, err)
+			os.Exit(1)
+		}
+
+		trees = append(trees, t...)
+	}
+
+	if jobs < 1 {
+		jobs = 1
+	}
+
+	if checkLinkTargets && !offline {
+		if linkConcurrency < 1 {
+			linkConcurrency = 1
+		}
+
+		if err := resolveLinks(trees); err != nil {
+			const errFmt = "failed to check links: %s\n"
+			fmt.Fprintf(os.Stderr, errFmt, err)
+			os.Exit(1)
+		}
+	}
+
+	if lineEndings == "consistent" {
+		expectedLineEnding = dominantLineEnding(trees)
+	}
+
+	defaultChangelogCodes = loadDefaultChangelogCodes(trees)
+
+	if useFileList {
+		validateFileList(trees, boilerplateRegexp, start)
+		return
+	}
+
+	locales := make([]string, 0)
+	for _, t := range trees {
+		locales = append(locales, t.locales...)
+	}
+
+	errs := make([]error, 0)
+	if inShard(0) { // only the first shard runs the checks that span locales
+		errs = append(errs, checkFramefile(framefilePath, locales)...)
+		for _, t := range trees {
+			errs = append(errs, checkDefaultLocale(t)...)
+			if minLocales > 0 {
+				errs = append(errs, checkMinLocales(t)...)
+			}
+		}
+	}
+
+	if _, err := os.Stat(framefilePath); err == nil {
+		skipFramedScreenshots = true
+	}
+
+	localeIndex := -1
+	names := make([]string, 0, len(locales))
+	localePaths := make([]string, 0, len(locales))
+	for _, t := range trees {
+		for _, locale := range t.locales {
+			if localeIndex++; !inShard(localeIndex) {
+				continue
+			}
+
+			localePath := filepath.Join(t.path, locale)
+			if !isChanged(localePath) && !isChanged(layout.changelogsPath(localePath)) {
+				continue
+			}
+
+			names = append(names, localeName(t, locale))
+			localePaths = append(localePaths, localePath)
+		}
+	}
+
+	localeResults := make([]localeResult, 0, len(localePaths))
+	for i, v := range validateLocales(localePaths, boilerplateRegexp) {
+		errs = append(errs, v.errs...)
+		lr := newReport(v.errs)
+		localeResults = append(localeResults, localeResult{
+			Locale:     names[i],
+			Errors:     lr.Errors,
+			Warnings:   lr.Warnings,
+			DurationMs: v.duration.Milliseconds(),
+		})
+	}
+
+	if outputFormat == "json" {
+		printJSONReport(errs, localeResults)
+	} else {
+		printTextReport(errs, localeResults)
+	}
+
+	r := newReport(errs)
+	printRunResult(r, len(locales), time.Since(start))
+	if historyPath != "" {
+		if err := appendHistory(historyPath, r); err != nil {
+			const errFmt = "failed to write history %q: %s\n"
+			fmt.Fprintf(os.Stderr, errFmt, historyPath, err)
+			os.Exit(1)
+		}
+	}
+
+	if r.Errors > 0 {
+		os.Exit(1)
+	}
+}
+
+// validateStdin validates the content read from stdin as if it were the
+// `-stdin-filename` file, e.g. to lint unsaved editor buffers. It only reports
+// the issues of that file.
+func validateStdin(boilerplateRegexp *regexp.Regexp, start time.Time) {
+	inRoot := false
+	for _, p := range fastlanePaths.paths {
+		rel, err := filepath.Rel(p, stdinFilename)
+		inRoot = inRoot || err == nil && !strings.HasPrefix(rel, "..") && strings.Contains(rel, string(filepath.Separator))
+	}
+
+	if stdinFilename == "" || !inRoot {
+		const errFmt = "-stdin-filename must be a file in a locale directory of %q\n"
+		fmt.Fprintf(os.Stderr, errFmt, fastlanePaths.String())
+		os.Exit(2)
+	}
+
+	var err error
+	if stdinPath, err = filepath.Abs(stdinFilename); err == nil {
+		stdinContent, err = ioutil.ReadAll(os.Stdin)
+	}
+
 	if err != nil {
-		const errFmt = "failed to read directory %q: %s\n"
-		fmt.Fprintf(os.Stderr, errFmt, fastlanePath, err)
+		const errFmt = "failed to read stdin: %s\n"
+		fmt.Fprintf(os.Stderr, errFmt, err)
 		os.Exit(1)
 	}
 
 	errs := make([]error, 0)
-	for _, f := range files {
-		if !f.IsDir() {
-			// we are only interested in directories
-			continue
+	for _, err := range validateLocale(layout.localePath(stdinFilename), boilerplateRegexp) {
+		if ve, ok := err.(*validationError); ok && isStdinFile(ve.File) {
+			errs = append(errs, err)
 		}
+	}
 
-		localePath := filepath.Join(fastlanePath, f.Name())
-		if usePlayStoreLocales && !playStoreLocales.contains(f.Name()) {
-			const errFmt = "Google Play doesn't recognise %q locale: closest alternative is %q"
-			errs = append(errs, &validationError{
-				File: localePath,
-				Err:  fmt.Errorf(errFmt, f.Name(), playStoreLocales.closestMatch(f.Name())),
-			})
+	if outputFormat == "json" {
+		printJSONReport(errs, nil)
+	} else {
+		printTextReport(errs, nil)
+	}
+
+	r := newReport(errs)
+	printRunResult(r, 1, time.Since(start))
+	if r.Errors > 0 {
+		os.Exit(1)
+	}
+}
+
+// validateFileList validates the locales of the metadata files listed on
+// stdin, one per line, e.g. the staged files in a pre-commit hook. It only
+// reports the issues of the listed files.
+func validateFileList(trees []localeTree, boilerplateRegexp *regexp.Regexp, start time.Time) {
+	listed := make(map[string]bool)
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			if abs, err := filepath.Abs(line); err == nil {
+				listed[abs] = true
+			}
 		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		const errFmt = "failed to read stdin: %s\n"
+		fmt.Fprintf(os.Stderr, errFmt, err)
+		os.Exit(1)
+	}
 
-		imagesPath := filepath.Join(localePath, "images")
-		changelogsPath := filepath.Join(localePath, "changelogs")
-		errs = append(errs, checkDescriptiveTexts(localePath)...)
-		errs = append(errs, checkImages(imagesPath)...)
-		errs = append(errs, checkChangelogs(changelogsPath)...)
+	names := make([]string, 0)
+	localePaths := make([]string, 0)
+	for _, t := range trees {
+		for _, locale := range t.locales {
+			localePath := filepath.Join(t.path, locale)
+			if !hasListedFile(listed, localePath) && !hasListedFile(listed, layout.changelogsPath(localePath)) {
+				continue
+			}
+
+			names = append(names, localeName(t, locale))
+			localePaths = append(localePaths, localePath)
+		}
 	}
 
-	fmt.Println("found", len(errs), "errors!")
-	for _, err := range errs {
-		if ve, ok := err.(*validationError); ok && useFileAnnotations {
-			ve.annotateGitHubFile()
+	errs := make([]error, 0)
+	localeResults := make([]localeResult, 0, len(localePaths))
+	for i, v := range validateLocales(localePaths, boilerplateRegexp) {
+		localeErrs := make([]error, 0)
+		for _, err := range v.errs {
+			if ve, ok := err.(*validationError); ok && hasListedFile(listed, ve.File) {
+				localeErrs = append(localeErrs, err)
+			}
 		}
 
-		fmt.Fprintln(os.Stderr, err.Error())
+		errs = append(errs, localeErrs...)
+		lr := newReport(localeErrs)
+		localeResults = append(localeResults, localeResult{
+			Locale:     names[i],
+			Errors:     lr.Errors,
+			Warnings:   lr.Warnings,
+			DurationMs: v.duration.Milliseconds(),
+		})
+	}
+
+	if outputFormat == "json" {
+		printJSONReport(errs, localeResults)
+	} else {
+		printTextReport(errs, localeResults)
 	}
 
-	if len(errs) > 0 {
+	r := newReport(errs)
+	printRunResult(r, len(localeResults), time.Since(start))
+	if r.Errors > 0 {
 		os.Exit(1)
 	}
 }
 
+// hasListedFile reports whether `path` is one of the `listed` files or a
+// directory containing one of them.
+func hasListedFile(listed map[string]bool, path string) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+
+	if listed[abs] {
+		return true
+	}
+
+	for p := range listed {
+		if strings.HasPrefix(p, abs+string(filepath.Separator)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// localeName returns the name of the `locale` in `tree` for reports. In flavor
+// layouts, it is prefixed with the name of the flavor, and when validating
+// several fastlane paths, with the fastlane path.
+func localeName(tree localeTree, locale string) string {
+	name := path.Join(tree.flavor, locale)
+	if len(fastlanePaths.paths) > 1 {
+		name = path.Join(filepath.ToSlash(tree.root), name)
+	}
+
+	return name
+}
+
+// localeValidation is the outcome of validating a locale.
+type localeValidation struct {
+	errs     []error
+	duration time.Duration
+}
+
+// validateLocales validates the locales at `localePaths` on `-j` workers. It
+// returns their outcomes in the order of `localePaths`, so that the reports
+// don't depend on the scheduling.
+func validateLocales(localePaths []string, boilerplateRegexp *regexp.Regexp) []localeValidation {
+	results := make([]localeValidation, len(localePaths))
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < jobs && w < len(localePaths); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				localeStart := time.Now()
+				results[i].errs = validateLocale(localePaths[i], boilerplateRegexp)
+				results[i].duration = time.Since(localeStart)
+			}
+		}()
+	}
+
+	for i := range localePaths {
+		indices <- i
+	}
+
+	close(indices)
+	wg.Wait()
+	return results
+}
+
+// validateLocale runs all checks for the locale at `localePath`. It returns a
+// slice of `error` with all IO and validation errors.
+func validateLocale(localePath string, boilerplateRegexp *regexp.Regexp) []error {
+	locale := filepath.Base(localePath)
+	errs := make([]error, 0)
+	if usePlayStoreLocales && !playStoreLocales.contains(locale) {
+		const errFmt = "Google Play doesn't recognise %q locale: closest alternative is %q"
+		errs = append(errs, &validationError{
+			File: localePath,
+			Rule: "play-store-locale",
+			Err:  fmt.Errorf(errFmt, locale, playStoreLocales.closestMatch(locale)),
+		})
+	}
+
+	imagesPath := filepath.Join(localePath, layout.imagesDir)
+	changelogsPath := layout.changelogsPath(localePath)
+	errs = append(errs, checkSymlinks(localePath)...)
+	errs = append(errs, checkStructure(localePath)...)
+	errs = append(errs, checkFileCase(localePath)...)
+	errs = append(errs, checkImageEntries(localePath)...)
+	errs = append(errs, checkDescriptiveTexts(localePath)...)
+	errs = append(errs, checkCustomTextFiles(localePath)...)
+	errs = append(errs, checkTitleSymbols(layout.textPath(localePath, "title.txt"))...)
+	errs = append(errs, checkTitleClaims(layout.textPath(localePath, "title.txt"))...)
+	errs = append(errs, checkEmojiPolicy(localePath)...)
+	errs = append(errs, checkInvisibleCharacters(localePath)...)
+	errs = append(errs, checkSingleLine(localePath)...)
+	errs = append(errs, checkShouting(localePath)...)
+	errs = append(errs, checkEncoding(layout.textPath(localePath, "video.txt"))...)
+	errs = append(errs, checkConflictMarkers(layout.textPath(localePath, "video.txt"))...)
+	errs = append(errs, checkLineEndings(layout.textPath(localePath, "video.txt"))...)
+	errs = append(errs, checkWhitespace(layout.textPath(localePath, "video.txt"))...)
+	errs = append(errs, checkVideoURL(layout.textPath(localePath, "video.txt"))...)
+	errs = append(errs, checkLinks(localePath)...)
+	if activeProfile.htmlTags != nil {
+		errs = append(errs, checkHTMLTags(layout.textPath(localePath, "full_description.txt"))...)
+	}
+
+	errs = append(errs, checkMarkdown(localePath)...)
+	errs = append(errs, checkKeywordStuffing(layout.textPath(localePath, "full_description.txt"))...)
+	errs = append(errs, checkDescriptionOverlap(localePath)...)
+	errs = append(errs, checkRepeatedWords(localePath)...)
+	errs = append(errs, checkLocaleScript(localePath)...)
+	errs = append(errs, checkLocaleLanguage(localePath)...)
+	errs = append(errs, checkUntranslated(localePath)...)
+	errs = append(errs, checkImages(imagesPath)...)
+	errs = append(errs, checkRequiredAssets(localePath)...)
+	errs = append(errs, checkChangelogs(changelogsPath)...)
+	if layout.releaseNotesDir != "" {
+		return errs // release notes aren't named after version codes
+	}
+
+	errs = append(errs, checkBoilerplateChangelogs(changelogsPath, boilerplateRegexp)...)
+	if locale == defaultLocale {
+		errs = append(errs, checkReleaseChangelog(changelogsPath)...)
+	}
+
+	errs = append(errs, checkStaleChangelogs(changelogsPath)...)
+	errs = append(errs, checkChangelogTranslations(localePath)...)
+	if locale == defaultLocale || requireChangelogEverywhere {
+		errs = append(errs, checkRequiredChangelog(changelogsPath)...)
+	}
+
+	return errs
+}
+
 // checkDescriptiveTexts checks *.txt files in metadata. It returns a slice of
 // `error` with all IO and validation errors.
 func checkDescriptiveTexts(localePath string) []error {
-	descriptiveFileLengths := map[string]int{
-		"title.txt":             30,
-		"short_description.txt": 80,
-		"full_description.txt":  4000,
+	locale := filepath.Base(localePath)
+	maxLengths := cfg.textLimits(locale)
+	minLengths := cfg.textMinLengths(locale)
+	files := make([]string, 0, len(maxLengths))
+	for file := range maxLengths {
+		files = append(files, file)
+	}
+
+	for file := range minLengths {
+		if _, ok := maxLengths[file]; !ok {
+			files = append(files, file)
+		}
 	}
 
 	errs := make([]error, 0)
-	for file, length := range descriptiveFileLengths {
-		file = filepath.Join(localePath, file)
+	for _, name := range files {
+		file := layout.textPath(localePath, name)
 		count, err := getCharacterCount(file)
-		if err != nil {
-			const errFmt = "failed to read file %q: %w"
-			errs = append(errs, fmt.Errorf(errFmt, file, err))
-		} else if count > length {
+		if os.IsNotExist(err) {
+			errs = append(errs, &validationError{
+				File: file,
+				Rule: "required-file",
+				Err:  fmt.Errorf("required file is missing"),
+			})
+			continue
+		} else if err != nil {
+			errs = append(errs, readError(file, err))
+			continue
+		}
+
+		if count == 0 {
+			errs = append(errs, emptyFileError(file))
+			continue
+		}
+
+		if length, ok := maxLengths[name]; ok && count > length {
+			const errFmt = "content length exceeded: expected=%d, got=%d"
+			errs = append(errs, &validationError{
+				File: file,
+				Rule: "text-length",
+				Err:  fmt.Errorf(errFmt, length, count),
+			})
+		}
+
+		if length := minLengths[name]; count < length && !containsString(skipMinLength.paths, name) {
+			const errFmt = "content is too short: expected>=%d, got=%d"
+			errs = append(errs, &validationError{
+				File: file,
+				Rule: "text-min-length",
+				Err:  fmt.Errorf(errFmt, length, count),
+			})
+		}
+
+		errs = append(errs, checkEncoding(file)...)
+		errs = append(errs, checkConflictMarkers(file)...)
+		errs = append(errs, checkLineEndings(file)...)
+		errs = append(errs, checkWhitespace(file)...)
+		errs = append(errs, checkPlaceholders(file)...)
+		errs = append(errs, checkBannedWords(file)...)
+		errs = append(errs, checkProfanity(file)...)
+		errs = append(errs, checkSpelling(file)...)
+	}
+
+	return errs
+}
+
+// checkCustomTextFiles checks the additional text files declared in the config.
+// It returns a slice of `error` with all IO and validation errors.
+func checkCustomTextFiles(localePath string) []error {
+	errs := make([]error, 0)
+	for _, spec := range cfg.TextFiles {
+		file := filepath.Join(localePath, spec.Name)
+		count, err := getCharacterCount(file)
+		if os.IsNotExist(err) {
+			if spec.Required {
+				errs = append(errs, &validationError{
+					File: file,
+					Rule: "required-file",
+					Err:  fmt.Errorf("required file is missing"),
+				})
+			}
+
+			continue
+		} else if err != nil {
+			errs = append(errs, readError(file, err))
+			continue
+		}
+
+		if spec.MaxLength > 0 && count > spec.MaxLength {
 			const errFmt = "content length exceeded: expected=%d, got=%d"
 			errs = append(errs, &validationError{
 				File: file,
-				Err:  fmt.Errorf(errFmt, length, count),
+				Rule: "text-length",
+				Err:  fmt.Errorf(errFmt, spec.MaxLength, count),
 			})
 		}
+
+		errs = append(errs, checkEncoding(file)...)
+		errs = append(errs, checkConflictMarkers(file)...)
+		errs = append(errs, checkLineEndings(file)...)
+		errs = append(errs, checkWhitespace(file)...)
+		errs = append(errs, checkProfanity(file)...)
+		for _, rule := range spec.Rules {
+			errs = append(errs, textFileRules[rule](file)...)
+		}
 	}
 
 	return errs
 }
 
-// getCharacterCount counts the utf-8 characters in the given file.
+// getCharacterCount counts the characters in the given file according to
+// `-count-mode`. It returns a `*validationError` if the file doesn't contain
+// plain text, e.g. a renamed document or image.
 func getCharacterCount(filePath string) (int, error) {
-	content, err := ioutil.ReadFile(filePath)
+	content, err := readFile(filePath)
 	if err != nil {
 		return 0, err
 	}
 
-	return utf8.RuneCountInString(strings.TrimSpace(string(content))), nil
+	if kind := sniffBinary(content); kind != "" {
+		const errFmt = "file is not plain text: detected %s"
+		return 0, &validationError{
+			File: filePath,
+			Rule: "binary-content",
+			Err:  fmt.Errorf(errFmt, kind),
+		}
+	}
+
+	return countCharacters(strings.TrimSpace(string(content))), nil
+}
+
+// emptyFileError returns the error to report for the text file at `filePath`
+// that is empty or only contains whitespace. supply uploads it as an empty
+// string, blanking out the existing store content.
+func emptyFileError(filePath string) error {
+	return &validationError{
+		File: filePath,
+		Rule: "empty-file",
+		Err:  fmt.Errorf("file is empty or only contains whitespace: supply would blank out the store content"),
+	}
+}
+
+// readError returns the error to report when reading the text file at
+// `filePath` fails.
+func readError(filePath string, err error) error {
+	if ve, ok := err.(*validationError); ok {
+		return ve
+	}
+
+	const errFmt = "failed to read file %q: %w"
+	return fmt.Errorf(errFmt, filePath, err)
 }
 
 // checkImages checks image assets in `images/*` including screenshots. It
 // returns a slice of `error` with all IO and validation errors.
 func checkImages(imagesPath string) []error {
-	files, err := ioutil.ReadDir(imagesPath)
+	files, err := readDir(imagesPath)
 	// since directory is optional, ignore 'not exist' errors.
 	if err != nil && !os.IsNotExist(err) {
 		const errFmt = "failed to read directory %q: %w"
@@ -150,79 +1112,152 @@ func checkImages(imagesPath string) []error {
 
 	errs := make([]error, 0)
 	for _, file := range files {
-		if file.IsDir() {
-			if strings.HasSuffix(file.Name(), "Screenshots") {
-				errs = append(errs, checkScreenshots(filepath.Join(imagesPath, file.Name()))...)
+		filePath := filepath.Join(imagesPath, file.Name())
+		if !file.IsDir() {
+			if isUnusedImage(file.Name()) {
+				continue // reported by checkImageEntries
 			}
 
+			name := strings.TrimSuffix(filepath.Base(file.Name()), filepath.Ext(file.Name()))
+			errs = append(errs, checkImage(filePath, name)...)
 			continue
 		}
 
-		filePath := filepath.Join(imagesPath, file.Name())
-		config, err := getImageConfig(filePath)
-		if err != nil {
-			const errFmt = "failed to read image %q: %w"
-			errs = append(errs, fmt.Errorf(errFmt, filePath, err))
-			continue
+		name := layout.imageName(file.Name())
+		if strings.HasSuffix(name, "Screenshots") {
+			errs = append(errs, checkScreenshots(filePath)...)
+		} else if layout.imageDirs != nil {
+			errs = append(errs, checkImageDir(filePath, name)...)
 		}
+	}
 
-		switch name := strings.TrimSuffix(filepath.Base(file.Name()), filepath.Ext(file.Name())); name {
-		case "icon":
-			if config.width != config.height || config.width != 512 {
-				const errFmt = "icon must be 512x512: got=%dx%d"
-				errs = append(errs, &validationError{
-					File: filePath,
-					Err:  fmt.Errorf(errFmt, config.width, config.height),
-				})
-			}
-			if config.format != "png" {
-				errs = append(errs, &validationError{
-					File: filePath,
-					Err:  fmt.Errorf("icon must be a PNG"),
-				})
-			}
-		case "featureGraphic":
-			if config.width != 1024 || config.height != 500 {
-				const errFmt = "featureGraphic must be 1024x500: got=%dx%d"
-				errs = append(errs, &validationError{
-					File: filePath,
-					Err:  fmt.Errorf(errFmt, config.width, config.height),
-				})
-			}
-			if !config.opaque {
-				errs = append(errs, &validationError{
-					File: filePath,
-					Err:  fmt.Errorf("featureGraphic must be opaque and must not have the alpha channel"),
-				})
-			}
-		case "promoGraphic":
-			if config.width != 180 || config.height != 120 {
-				const errFmt = "promoGraphic must be 180x120: got=%dx%d"
-				errs = append(errs, &validationError{
-					File: filePath,
-					Err:  fmt.Errorf(errFmt, config.width, config.height),
-				})
-			}
-			if !config.opaque {
-				errs = append(errs, &validationError{
-					File: filePath,
-					Err:  fmt.Errorf("promoGraphic must be opaque and must not have the alpha channel"),
-				})
-			}
-		case "tvBanner":
-			if config.width != 1280 || config.height != 720 {
-				const errFmt = "tvBanner must be 1280x720: got=%dx%d"
-				errs = append(errs, &validationError{
-					File: filePath,
-					Err:  fmt.Errorf(errFmt, config.width, config.height),
-				})
-			}
-			if !config.opaque {
-				errs = append(errs, &validationError{
-					File: filePath,
-					Err:  fmt.Errorf("tvBanner must be opaque and must not have the alpha channel"),
-				})
-			}
+	return errs
+}
+
+// checkImageDir checks the images in `dirPath` that all are the image with the
+// fastlane `name`, e.g. in the Gradle Play Publisher layout.
+func checkImageDir(dirPath, name string) []error {
+	files, err := readDir(dirPath)
+	if err != nil {
+		const errFmt = "failed to read directory %q: %w"
+		return []error{fmt.Errorf(errFmt, dirPath, err)}
+	}
+
+	errs := make([]error, 0)
+	for _, file := range files {
+		if !file.IsDir() {
+			errs = append(errs, checkImage(filepath.Join(dirPath, file.Name()), name)...)
+		}
+	}
+
+	return errs
+}
+
+// checkImage checks the image at `filePath` against the requirements of the
+// image with the fastlane `name`, e.g. `icon` or `featureGraphic`.
+func checkImage(filePath, name string) []error {
+	if ninePatchErrs := checkNinePatch(filePath); len(ninePatchErrs) > 0 {
+		return ninePatchErrs
+	}
+
+	errs := checkImageFileSize(filePath, cfg.imageMaxBytes(name))
+	config, err := getImageConfig(filePath)
+	if err != nil {
+		return append(errs, imageReadError(filePath, err))
+	}
+
+	if config.format == "jpeg" {
+		errs = append(errs, checkJPEGQuality(filePath, config)...)
+		errs = append(errs, checkJPEGColorModel(filePath, config)...)
+	}
+
+	if config.format == "png" {
+		errs = append(errs, checkAnimatedPNG(filePath, config)...)
+	}
+
+	if deepImageCheck {
+		_, err := config.decode()
+		errs = append(errs, checkImageIntegrity(filePath, err)...)
+	}
+
+	errs = append(errs, checkInterlacing(filePath, config)...)
+	errs = append(errs, checkColorProfile(filePath, config)...)
+	errs = append(errs, checkEXIFOrientation(filePath, config)...)
+
+	if !activeProfile.strictGraphics {
+		return errs
+	}
+
+	if config.format == "png" {
+		errs = append(errs, checkPNGFormat(filePath, config)...)
+	}
+
+	switch name {
+	case "icon":
+		if config.width != config.height || config.width != 512 {
+			const errFmt = "icon must be 512x512: got=%dx%d"
+			errs = append(errs, &validationError{
+				File: filePath,
+				Rule: "icon-size",
+				Err:  fmt.Errorf(errFmt, config.width, config.height),
+			})
+		}
+		if config.format != "png" {
+			errs = append(errs, &validationError{
+				File: filePath,
+				Rule: "icon-format",
+				Err:  fmt.Errorf("icon must be a PNG"),
+			})
+		}
+		errs = append(errs, checkIconPadding(filePath, config)...)
+		errs = append(errs, checkIconAlpha(filePath, config)...)
+	case "featureGraphic":
+		if config.width != 1024 || config.height != 500 {
+			const errFmt = "featureGraphic must be 1024x500: got=%dx%d"
+			errs = append(errs, &validationError{
+				File: filePath,
+				Rule: "graphic-size",
+				Err:  fmt.Errorf(errFmt, config.width, config.height),
+			})
+		}
+		if !config.opaque {
+			errs = append(errs, &validationError{
+				File: filePath,
+				Rule: "graphic-opacity",
+				Err:  fmt.Errorf("featureGraphic must be opaque and must not have the alpha channel"),
+			})
+		}
+	case "promoGraphic":
+		if config.width != 180 || config.height != 120 {
+			const errFmt = "promoGraphic must be 180x120: got=%dx%d"
+			errs = append(errs, &validationError{
+				File: filePath,
+				Rule: "graphic-size",
+				Err:  fmt.Errorf(errFmt, config.width, config.height),
+			})
+		}
+		if !config.opaque {
+			errs = append(errs, &validationError{
+				File: filePath,
+				Rule: "graphic-opacity",
+				Err:  fmt.Errorf("promoGraphic must be opaque and must not have the alpha channel"),
+			})
+		}
+	case "tvBanner":
+		if config.width != 1280 || config.height != 720 {
+			const errFmt = "tvBanner must be 1280x720: got=%dx%d"
+			errs = append(errs, &validationError{
+				File: filePath,
+				Rule: "graphic-size",
+				Err:  fmt.Errorf(errFmt, config.width, config.height),
+			})
+		}
+		if !config.opaque {
+			errs = append(errs, &validationError{
+				File: filePath,
+				Rule: "graphic-opacity",
+				Err:  fmt.Errorf("tvBanner must be opaque and must not have the alpha channel"),
+			})
 		}
 	}
 
@@ -232,96 +1267,270 @@ func checkImages(imagesPath string) []error {
 // checkScreenshots checks all screenshot images. It returns a slice of `error`
 // with all IO and validation errors.
 func checkScreenshots(screenshotsPath string) []error {
-	files, err := ioutil.ReadDir(screenshotsPath)
+	files, err := readDir(screenshotsPath)
 	if err != nil {
 		const errFmt = "failed to read directory %q: %w"
 		return []error{fmt.Errorf(errFmt, screenshotsPath, err)}
 	}
 
+	spec := cfg.screenshotSpec(layout.imageName(filepath.Base(screenshotsPath)))
 	errs := make([]error, 0)
+	digests := make([]screenshotDigest, 0, len(files))
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		if !file.IsDir() && !(skipFramedScreenshots && isFramedScreenshot(file.Name())) {
+			names = append(names, file.Name())
+		}
+	}
+
+	count := len(names)
+
+	if spec.MaxCount > 0 && count > spec.MaxCount {
+		const errFmt = "expected at most %d screenshots: got=%d"
+		errs = append(errs, &validationError{
+			File: screenshotsPath,
+			Rule: "screenshot-count",
+			Err:  fmt.Errorf(errFmt, spec.MaxCount, count),
+		})
+	}
+
+	errs = append(errs, checkScreenshotNames(screenshotsPath, names)...)
 	for _, file := range files {
+		if skipFramedScreenshots && isFramedScreenshot(file.Name()) {
+			continue // generated by frameit
+		}
+
 		imagePath := filepath.Join(screenshotsPath, file.Name())
+		if ninePatchErrs := checkNinePatch(imagePath); len(ninePatchErrs) > 0 {
+			errs = append(errs, ninePatchErrs...)
+			continue
+		}
+
+		errs = append(errs, checkImageFileSize(imagePath, spec.MaxBytes)...)
 		config, err := getImageConfig(imagePath)
 		if err != nil {
-			const errFmt = "failed to read image %q: %w"
-			errs = append(errs, fmt.Errorf(errFmt, imagePath, err))
+			errs = append(errs, imageReadError(imagePath, err))
 			continue
 		}
 
-		if config.width < 320 || config.width > 3840 {
-			const errFmt = "width should be in range 320px-3840px: got=%dpx"
+		// decode the screenshot once for all the checks of its pixels
+		var img image.Image
+		if deepImageCheck || letterboxThreshold > 0 || duplicateDistance >= 0 {
+			img, err = config.decode()
+			if deepImageCheck {
+				errs = append(errs, checkImageIntegrity(imagePath, err)...)
+			}
+		}
+
+		digests = append(digests, digestScreenshot(imagePath, config, img))
+		if config.format == "jpeg" {
+			errs = append(errs, checkJPEGQuality(imagePath, config)...)
+			errs = append(errs, checkJPEGColorModel(imagePath, config)...)
+		}
+
+		if config.format == "png" {
+			errs = append(errs, checkAnimatedPNG(imagePath, config)...)
+		}
+
+		errs = append(errs, checkInterlacing(imagePath, config)...)
+		errs = append(errs, checkColorProfile(imagePath, config)...)
+		errs = append(errs, checkEXIFOrientation(imagePath, config)...)
+
+		if config.format == "png" && activeProfile.strictGraphics {
+			errs = append(errs, checkPNGFormat(imagePath, config)...)
+		}
+
+		if config.width < spec.MinEdge || spec.MaxEdge > 0 && config.width > spec.MaxEdge {
+			const errFmt = "width should be in range %dpx-%dpx: got=%dpx"
 			errs = append(errs, &validationError{
 				File: imagePath,
-				Err:  fmt.Errorf(errFmt, config.width),
+				Rule: "screenshot-size",
+				Err:  fmt.Errorf(errFmt, spec.MinEdge, spec.MaxEdge, config.width),
 			})
 		}
 
-		if config.height < 320 || config.height > 3840 {
-			const errFmt = "height should be in range 320px-3840px: got=%dpx"
+		if config.height < spec.MinEdge || spec.MaxEdge > 0 && config.height > spec.MaxEdge {
+			const errFmt = "height should be in range %dpx-%dpx: got=%dpx"
 			errs = append(errs, &validationError{
 				File: imagePath,
-				Err:  fmt.Errorf(errFmt, config.height),
+				Rule: "screenshot-size",
+				Err:  fmt.Errorf(errFmt, spec.MinEdge, spec.MaxEdge, config.height),
 			})
 		}
 
 		width := float64(config.width)
 		height := float64(config.height)
 		ratio := math.Max(width, height) / math.Min(height, width)
-		if ratio > 2.3 {
-			const errFmt = "'max:min' edge radio should be at most 2.3: got=%.2f"
+		if spec.MaxAspectRatio > 0 && ratio > spec.MaxAspectRatio {
+			const errFmt = "'max:min' edge radio should be at most %.2f: got=%.2f"
+			errs = append(errs, &validationError{
+				File: imagePath,
+				Rule: "screenshot-aspect-ratio",
+				Err:  fmt.Errorf(errFmt, spec.MaxAspectRatio, ratio),
+			})
+		}
+
+		if len(spec.AspectRatios) > 0 && !matchesAspectRatio(spec.AspectRatios, width/height) {
+			const errFmt = "width:height ratio must be %s: got=%.2f"
+			errs = append(errs, &validationError{
+				File: imagePath,
+				Rule: "screenshot-aspect-ratio",
+				Err:  fmt.Errorf(errFmt, strings.Join(spec.AspectRatios, " or "), width/height),
+			})
+		}
+
+		if resolution := fmt.Sprintf("%dx%d", config.width, config.height); len(spec.Resolutions) > 0 && !containsString(spec.Resolutions, resolution) {
+			const errFmt = "resolution must be one of %s: got=%s"
 			errs = append(errs, &validationError{
 				File: imagePath,
-				Err:  fmt.Errorf(errFmt, ratio),
+				Rule: "screenshot-size",
+				Err:  fmt.Errorf(errFmt, strings.Join(spec.Resolutions, ", "), resolution),
+			})
+		}
+
+		if shortEdge := int(math.Min(width, height)); shortEdge < spec.RecommendedMinShortEdge {
+			const errFmt = "short edge should be at least %dpx to qualify for featuring on Google Play: got=%dpx"
+			errs = append(errs, &validationError{
+				File:     imagePath,
+				Rule:     "screenshot-resolution",
+				Err:      fmt.Errorf(errFmt, spec.RecommendedMinShortEdge, shortEdge),
+				Severity: severityWarning,
+			})
+		}
+
+		if !config.opaque {
+			errs = append(errs, &validationError{
+				File:     imagePath,
+				Rule:     "screenshot-transparency",
+				Err:      fmt.Errorf("screenshot has transparency: Google Play flattens it onto an arbitrary background"),
+				Severity: severityWarning,
 			})
 		}
+
+		errs = append(errs, checkLetterbox(imagePath, img)...)
 	}
 
-	return errs
+	return append(errs, checkDuplicateScreenshots(digests)...)
 }
 
-// getImageConfig returns imageConfig for the given image file. returns an error
-// it is not able to read the image config.
-func getImageConfig(filePath string) (*imageConfig, error) {
-	file, err := os.Open(filePath)
+// imageReadError returns the validation error for the image that
+// getImageConfig refused to decode, or an error wrapping the failure to read
+// it.
+func imageReadError(filePath string, err error) error {
+	if ve, ok := err.(*validationError); ok {
+		return ve
+	}
+
+	const errFmt = "failed to read image %q: %w"
+	return fmt.Errorf(errFmt, filePath, err)
+}
+
+// readImageContent reads the image at `filePath` and its header, refusing the
+// images beyond `-max-image-bytes` or `-max-image-pixels` with a validation
+// error, so that a decompression bomb can't exhaust the memory, and the images
+// in the formats that Play rejects.
+func readImageContent(filePath string) ([]byte, image.Config, string, error) {
+	if info, err := statFile(filePath); err == nil && maxImageBytes > 0 && info.Size() > maxImageBytes {
+		const errFmt = "image is too large to validate: expected<=%d bytes, got=%d bytes"
+		return nil, image.Config{}, "", &validationError{
+			File: filePath,
+			Rule: "image-too-large",
+			Err:  fmt.Errorf(errFmt, maxImageBytes, info.Size()),
+		}
+	}
+
+	file, err := openFile(filePath)
 	if err != nil {
-		return nil, err
+		return nil, image.Config{}, "", err
 	}
 
-	config, format, err := image.DecodeConfig(file)
+	defer file.Close()
+	content, err := ioutil.ReadAll(file)
 	if err != nil {
-		return nil, err
+		return nil, image.Config{}, "", err
 	}
 
-	opaque := false
-	if format == "png" { // need to check if image is opaque
-		if _, err = file.Seek(0, 0); err != nil {
-			return nil, err
+	if err := imageFormatError(filePath, content); err != nil {
+		return nil, image.Config{}, "", err
+	}
+
+	config, format, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return nil, image.Config{}, "", err
+	}
+
+	if pixels := int64(config.Width) * int64(config.Height); maxImagePixels > 0 && pixels > int64(maxImagePixels) {
+		const errFmt = "image is too large to decode: expected<=%d pixels, got=%dx%d"
+		return nil, image.Config{}, "", &validationError{
+			File: filePath,
+			Rule: "image-too-large",
+			Err:  fmt.Errorf(errFmt, maxImagePixels, config.Width, config.Height),
+		}
+	}
+
+	return content, config, format, nil
+}
+
+// matchesAspectRatio reports whether `ratio` is within 1% of any of the
+// width:height `ratios`.
+func matchesAspectRatio(ratios []string, ratio float64) bool {
+	for _, r := range ratios {
+		if expected, err := parseAspectRatio(r); err == nil && math.Abs(ratio-expected) <= expected*0.01 {
+			return true
 		}
+	}
 
-		image, _, err := image.Decode(file)
-		if err != nil {
+	return false
+}
+
+// getImageConfig returns imageConfig for the given image file. returns an error
+// it is not able to read the image config.
+func getImageConfig(filePath string) (*imageConfig, error) {
+	content, config, format, err := readImageContent(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &imageConfig{format: format, content: content}
+	c.opaque = format == "jpeg" // jpeg doesn't support the alpha channel
+	switch format {
+	case "png": // need to check if image is opaque
+		if c.chunks, err = parsePNGChunks(content); err != nil {
 			return nil, err
 		}
 
-		if oimage, ok := image.(interface{ Opaque() bool }); ok {
-			opaque = oimage.Opaque()
-		} else {
-			return nil, fmt.Errorf("failed to determine if image is opaque")
+		// decoding is slow, so only decode images whose alpha channel may
+		// have transparent pixels
+		c.opaque = !hasPNGAlpha(c.chunks)
+		if !c.opaque {
+			decoded, err := c.decode()
+			if err != nil {
+				return nil, err
+			}
+
+			if oimage, ok := decoded.(interface{ Opaque() bool }); ok {
+				c.opaque = oimage.Opaque()
+			} else {
+				return nil, fmt.Errorf("failed to determine if image is opaque")
+			}
 		}
+	case "jpeg":
+		c.segments, c.segmentsErr = parseJPEGSegments(content)
+	}
+
+	c.orientation = exifOrientation(c)
+	c.width, c.height = config.Width, config.Height
+	if c.orientation >= 5 {
+		c.width, c.height = config.Height, config.Width
 	}
 
-	return &imageConfig{
-		width:  config.Width,
-		height: config.Height,
-		opaque: opaque,
-		format: format,
-	}, nil
+	return c, nil
 }
 
 // checkChangelogs checks `changelogs/*.txt` files in metadata. It returns a
 // slice of `error` containing both IO and validation errors.
 func checkChangelogs(changelogsPath string) []error {
-	files, err := ioutil.ReadDir(changelogsPath)
+	files, err := readDir(changelogsPath)
 	// since directory is optional, ignore 'not exist' errors.
 	if err != nil && !os.IsNotExist(err) {
 		const errFmt = "failed to read directory %q: %w"
@@ -335,20 +1544,183 @@ func checkChangelogs(changelogsPath string) []error {
 		}
 
 		filePath := filepath.Join(changelogsPath, file.Name())
+		if layout.releaseNotesDir == "" && !changelogNameRegexp.MatchString(file.Name()) {
+			errs = append(errs, &validationError{
+				File: filePath,
+				Rule: "changelog-name",
+				Err:  fmt.Errorf("supply ignores this changelog: name must be default.txt or <versionCode>.txt with a positive version code"),
+			})
+			continue
+		}
+
 		count, err := getCharacterCount(filePath)
 		if err != nil {
-			const errFmt = "failed to read file %q: %w"
-			errs = append(errs, fmt.Errorf(errFmt, filePath, err))
+			errs = append(errs, readError(filePath, err))
+			continue
+		}
+
+		if count == 0 {
+			errs = append(errs, emptyFileError(filePath))
+			continue
 		}
 
-		const maxContentLength = 500
-		if count > maxContentLength {
+		if maxLength := cfg.changelogMaxLength(); count > maxLength {
 			const errFmt = "content length exceeded: expected=%d, got=%d"
 			errs = append(errs, &validationError{
 				File: filePath,
-				Err:  fmt.Errorf(errFmt, maxContentLength, count),
+				Rule: "text-length",
+				Err:  fmt.Errorf(errFmt, maxLength, count),
+			})
+		}
+
+		errs = append(errs, checkEncoding(filePath)...)
+		errs = append(errs, checkConflictMarkers(filePath)...)
+		errs = append(errs, checkMojibake(filePath)...)
+		errs = append(errs, checkLineEndings(filePath)...)
+		errs = append(errs, checkWhitespace(filePath)...)
+		errs = append(errs, checkPlainText(filePath)...)
+		errs = append(errs, checkPlaceholders(filePath)...)
+		errs = append(errs, checkBannedWords(filePath)...)
+		errs = append(errs, checkProfanity(filePath)...)
+		errs = append(errs, checkSpelling(filePath)...)
+	}
+
+	return errs
+}
+
+// checkRequiredChangelog checks that the changelog for the
+// `-require-changelog-for` version code exists, so that a release never ships
+// with the `default.txt` fallback or without release notes.
+func checkRequiredChangelog(changelogsPath string) []error {
+	if requiredChangelog <= 0 {
+		return nil
+	}
+
+	filePath := filepath.Join(changelogsPath, fmt.Sprintf("%d.txt", requiredChangelog))
+	if _, err := statFile(filePath); os.IsNotExist(err) || isSkipped(filePath) {
+		const errFmt = "changelog for version code %d is missing"
+		return []error{&validationError{
+			File: filePath,
+			Rule: "required-changelog",
+			Err:  fmt.Errorf(errFmt, requiredChangelog),
+		}}
+	}
+
+	return nil
+}
+
+// checkReleaseChangelog checks the changelog for the release version code in
+// the default locale. If the version code isn't specified, it picks the
+// changelog with the highest version code. An empty "what's new" is worse than
+// the fallback text, so it warns if the changelog is too short.
+func checkReleaseChangelog(changelogsPath string) []error {
+	filePath := latestChangelog(changelogsPath)
+	if versionCode > 0 {
+		filePath = filepath.Join(changelogsPath, fmt.Sprintf("%d.txt", versionCode))
+	}
+
+	if filePath == "" {
+		return nil
+	}
+
+	count, err := getCharacterCount(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil // supply uses `default.txt` in this case
+		}
+
+		if _, ok := err.(*validationError); ok {
+			return nil // already reported by checkChangelogs
+		}
+
+		const errFmt = "failed to read file %q: %w"
+		return []error{fmt.Errorf(errFmt, filePath, err)}
+	}
+
+	if count > 0 && count < minChangelogLength { // empty ones are reported by checkChangelogs
+		const errFmt = "release changelog is too short: expected>=%d, got=%d"
+		return []error{&validationError{
+			File:     filePath,
+			Rule:     "release-changelog",
+			Err:      fmt.Errorf(errFmt, minChangelogLength, count),
+			Severity: severityWarning,
+		}}
+	}
+
+	return nil
+}
+
+// latestChangelog returns the path of the changelog with the highest version
+// code in `changelogsPath`. It returns an empty string if there is none.
+func latestChangelog(changelogsPath string) string {
+	files, err := readDir(changelogsPath)
+	if err != nil {
+		return ""
+	}
+
+	latest := 0
+	for _, file := range files {
+		code, err := strconv.Atoi(strings.TrimSuffix(file.Name(), ".txt"))
+		if err == nil && !file.IsDir() && code > latest {
+			latest = code
+		}
+	}
+
+	if latest == 0 {
+		return ""
+	}
+
+	return filepath.Join(changelogsPath, fmt.Sprintf("%d.txt", latest))
+}
+
+// checkBoilerplateChangelogs warns if `boilerplateRunSize` or more consecutive
+// version codes share the exact same changelog text matching `pattern`, e.g.
+// "Bug fixes and improvements".
+func checkBoilerplateChangelogs(changelogsPath string, pattern *regexp.Regexp) []error {
+	if boilerplateRunSize < 2 {
+		return nil
+	}
+
+	files, err := readDir(changelogsPath)
+	if err != nil {
+		return nil // already reported by checkChangelogs
+	}
+
+	codes := make([]int, 0, len(files))
+	for _, file := range files {
+		code, err := strconv.Atoi(strings.TrimSuffix(file.Name(), ".txt"))
+		if err == nil && !file.IsDir() {
+			codes = append(codes, code)
+		}
+	}
+
+	sort.Ints(codes)
+	texts := make([]string, len(codes))
+	for i, code := range codes {
+		content, err := readFile(filepath.Join(changelogsPath, fmt.Sprintf("%d.txt", code)))
+		if err == nil {
+			texts[i] = strings.TrimSpace(string(content))
+		}
+	}
+
+	errs := make([]error, 0)
+	for start, end := 0, 1; end <= len(codes); end++ {
+		if end < len(codes) && texts[end] == texts[start] {
+			continue
+		}
+
+		runSize := end - start
+		if runSize >= boilerplateRunSize && texts[start] != "" && pattern.MatchString(texts[start]) {
+			const errFmt = "changelogs for %d consecutive version codes (%d-%d) share the same text: %q"
+			errs = append(errs, &validationError{
+				File:     filepath.Join(changelogsPath, fmt.Sprintf("%d.txt", codes[end-1])),
+				Rule:     "boilerplate-changelog",
+				Err:      fmt.Errorf(errFmt, runSize, codes[start], codes[end-1], texts[start]),
+				Severity: severityWarning,
 			})
 		}
+
+		start = end
 	}
 
 	return errs
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// manifestEntry describes a file in the asset manifest.
+type manifestEntry struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+	Width  int    `json:"width,omitempty"`
+	Height int    `json:"height,omitempty"`
+}
+
+type manifest struct {
+	Files []manifestEntry `json:"files"`
+}
+
+// manifestCommand writes a checksum manifest of the validated files in the
+// metadata directory, so that later pipeline stages can verify that nothing changed
+// between validation and upload.
+func manifestCommand(args []string) {
+	fs := flag.NewFlagSet("manifest", flag.ExitOnError)
+	output := fs.String("output", "", "write the manifest to this file instead of stdout")
+	fs.Parse(args)
+
+	m := &manifest{Files: make([]manifestEntry, 0)}
+	for _, p := range fastlanePaths.paths {
+		rm, err := buildManifest(p)
+		if err != nil {
+			const errFmt = "failed to build manifest: %s\n"
+			fmt.Fprintf(os.Stderr, errFmt, err)
+			os.Exit(1)
+		}
+
+		for _, e := range rm.Files {
+			if len(fastlanePaths.paths) > 1 { // attribute files to their fastlane path
+				e.Path = path.Join(filepath.ToSlash(p), e.Path)
+			}
+
+			m.Files = append(m.Files, e)
+		}
+	}
+
+	content, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		const errFmt = "failed to encode manifest: %s\n"
+		fmt.Fprintf(os.Stderr, errFmt, err)
+		os.Exit(1)
+	}
+
+	content = append(content, '\n')
+	if *output == "" {
+		os.Stdout.Write(content)
+		return
+	}
+
+	if err := ioutil.WriteFile(*output, content, 0o644); err != nil {
+		const errFmt = "failed to write file %q: %s\n"
+		fmt.Fprintf(os.Stderr, errFmt, *output, err)
+		os.Exit(1)
+	}
+}
+
+// buildManifest returns the manifest of the regular files in `rootPath` that
+// the validator covers. Like readDir, it leaves out skipped files and follows
+// symlinks with `-symlinks=follow`, and it leaves out the ignore files.
+func buildManifest(rootPath string) (*manifest, error) {
+	m := &manifest{Files: make([]manifestEntry, 0)}
+	err := addManifestDir(m, rootPath, rootPath, make(map[string]bool))
+	return m, err
+}
+
+// addManifestDir adds the files in `dirPath` and its subdirectories to the
+// manifest `m` of `rootPath`. `parents` contains the real paths of `dirPath`
+// and its parent directories, so that a symlink to one of them doesn't loop.
+func addManifestDir(m *manifest, rootPath, dirPath string, parents map[string]bool) error {
+	realPath, err := filepath.EvalSymlinks(dirPath)
+	if err != nil {
+		realPath = dirPath // archives don't have symlinks
+	}
+
+	if parents[realPath] {
+		return nil // reported by checkSymlinks
+	}
+
+	parents[realPath] = true
+	defer delete(parents, realPath)
+
+	files, err := readDir(dirPath)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		filePath := filepath.Join(dirPath, f.Name())
+		if f.IsDir() {
+			if err := addManifestDir(m, rootPath, filePath, parents); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if !f.Mode().IsRegular() || f.Name() == ".gitignore" || f.Name() == ".validateignore" {
+			continue
+		}
+
+		entry, err := newManifestEntry(rootPath, filePath)
+		if err != nil {
+			return err
+		}
+
+		m.Files = append(m.Files, entry)
+	}
+
+	return nil
+}
+
+// newManifestEntry returns the manifest entry of the file at `filePath`, whose
+// path is relative to `rootPath`. It reads the file once for both the checksum
+// and the dimensions, which don't require decoding the image.
+func newManifestEntry(rootPath, filePath string) (manifestEntry, error) {
+	relPath, err := filepath.Rel(rootPath, filePath)
+	if err != nil {
+		return manifestEntry{}, err
+	}
+
+	file, err := openFile(filePath)
+	if err != nil {
+		return manifestEntry{}, err
+	}
+
+	defer file.Close()
+	content, err := ioutil.ReadAll(file)
+	if err != nil {
+		return manifestEntry{}, err
+	}
+
+	checksum := sha256.Sum256(content)
+	entry := manifestEntry{
+		Path:   filepath.ToSlash(relPath),
+		Size:   int64(len(content)),
+		SHA256: hex.EncodeToString(checksum[:]),
+	}
+
+	if config, _, err := image.DecodeConfig(bytes.NewReader(content)); err == nil {
+		entry.Width = config.Width
+		entry.Height = config.Height
+	}
+
+	return entry, nil
+}
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"math"
+	"sort"
 	"strings"
 
 	"github.com/agnivade/levenshtein"
@@ -37,6 +40,36 @@ func (l locales) closestMatch(locale string) string {
 	return s
 }
 
+// sorted returns the locales that start with `prefix` in ascending order.
+func (l locales) sorted(prefix string) []string {
+	s := make([]string, 0, len(l))
+	for key := range l {
+		if strings.HasPrefix(key, prefix) {
+			s = append(s, key)
+		}
+	}
+
+	sort.Strings(s)
+	return s
+}
+
+// localesCommand prints the locales recognised by Google Play.
+func localesCommand(args []string) {
+	fs := flag.NewFlagSet("locales", flag.ExitOnError)
+	prefix := fs.String("prefix", "", "only print the locales starting with this prefix, e.g. en")
+	fs.Parse(args)
+
+	codes := playStoreLocales.sorted(*prefix)
+	if outputFormat == "json" {
+		printJSON(codes)
+		return
+	}
+
+	for _, c := range codes {
+		fmt.Println(c)
+	}
+}
+
 // playStoreLocales declares locales recognised by the Play Store Listing.
 var playStoreLocales = locales{
 	"af":     nil,
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// pngChunk is a chunk of a PNG file.
+type pngChunk struct {
+	kind string
+	data []byte
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// parsePNGChunks returns the chunks of the PNG `content` up to the first image
+// data chunk, which the ancillary chunks describing the image precede.
+func parsePNGChunks(content []byte) ([]pngChunk, error) {
+	if !bytes.HasPrefix(content, pngSignature) {
+		return nil, fmt.Errorf("missing PNG signature")
+	}
+
+	chunks := make([]pngChunk, 0)
+	for i := len(pngSignature); i+8 <= len(content); {
+		length := int(binary.BigEndian.Uint32(content[i:]))
+		if length < 0 || i+12+length > len(content) {
+			return nil, fmt.Errorf("invalid PNG chunk length at offset %d", i)
+		}
+
+		kind := string(content[i+4 : i+8])
+		chunks = append(chunks, pngChunk{kind: kind, data: content[i+8 : i+8+length]})
+		if kind == "IDAT" {
+			break
+		}
+
+		i += 12 + length // length, type, data and CRC
+	}
+
+	return chunks, nil
+}
+
+// pngHeader returns the data of the IHDR chunk among the PNG `chunks`: the
+// width, the height, the bit depth, the color type, and the compression, filter
+// and interlace methods.
+func pngHeader(chunks []pngChunk) ([]byte, error) {
+	if len(chunks) == 0 || chunks[0].kind != "IHDR" || len(chunks[0].data) != 13 {
+		return nil, fmt.Errorf("missing PNG IHDR chunk")
+	}
+
+	return chunks[0].data, nil
+}
+
+// pngColorTypes are the names of the PNG color types that Play re-encodes.
+var pngColorTypes = map[byte]string{
+	0: "grayscale",
+	3: "indexed",
+	4: "grayscale with alpha",
+}
+
+// checkPNGFormat warns if the PNG image at `filePath` isn't 8 bits per channel
+// RGB or RGBA, according to its IHDR chunk. Play re-encodes 16-bit, indexed and
+// grayscale PNGs, which may introduce artifacts.
+func checkPNGFormat(filePath string, config *imageConfig) []error {
+	ihdr, err := pngHeader(config.chunks)
+	if err != nil {
+		return nil // reported by getImageConfig
+	}
+
+	bitDepth, colorType := ihdr[8], ihdr[9]
+	issues := make([]string, 0, 2)
+	if name, ok := pngColorTypes[colorType]; ok {
+		issues = append(issues, "color type is "+name)
+	}
+
+	if bitDepth != 8 {
+		issues = append(issues, fmt.Sprintf("bit depth is %d", bitDepth))
+	}
+
+	if len(issues) > 0 {
+		const errFmt = "PNG should be 24-bit RGB or 32-bit RGBA: %s; Google Play may re-encode it with artifacts"
+		return []error{&validationError{
+			File:     filePath,
+			Rule:     "png-format",
+			Err:      fmt.Errorf(errFmt, strings.Join(issues, " and ")),
+			Severity: severityWarning,
+		}}
+	}
+
+	return nil
+}
+
+// hasPNGAlpha reports whether the PNG has an alpha channel, i.e. a color type
+// with alpha or a tRNS chunk, even if all of its pixels are opaque.
+func hasPNGAlpha(chunks []pngChunk) bool {
+	for _, c := range chunks {
+		if c.kind == "IHDR" && len(c.data) == 13 && (c.data[9] == 4 || c.data[9] == 6) || c.kind == "tRNS" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkAnimatedPNG checks that the PNG image isn't animated. APNG files decode
+// as their default image, but Play rejects their upload.
+func checkAnimatedPNG(filePath string, config *imageConfig) []error {
+	for _, c := range config.chunks {
+		if c.kind == "acTL" && len(c.data) >= 4 {
+			const errFmt = "animated PNGs aren't supported by Google Play: found %d frames"
+			return []error{&validationError{
+				File: filePath,
+				Rule: "animated-image",
+				Err:  fmt.Errorf(errFmt, binary.BigEndian.Uint32(c.data)),
+			}}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import "regexp"
+
+// storeProfile is a preset of the rules of a store that consumes the fastlane
+// metadata.
+type storeProfile struct {
+	// textLimits are the default maximum lengths of descriptive text files.
+	textLimits map[string]int
+	// changelogMaxLength is the default maximum length of changelogs.
+	changelogMaxLength int
+	// textMinLengths are the default minimum lengths of descriptive text files.
+	textMinLengths map[string]int
+	// screenshots are the default screenshot specs by directory name.
+	screenshots map[string]screenshotSpec
+	// strictGraphics enforces the exact dimensions and the opacity of the icon,
+	// the feature graphic, the promo graphic and the TV banner.
+	strictGraphics bool
+	// imageMaxBytes are the default maximum file sizes of the images by their
+	// fastlane names. Screenshot specs declare the limits of screenshots.
+	imageMaxBytes map[string]int64
+	// htmlTags are the HTML tags allowed in the full description. The full
+	// description isn't checked for HTML tags if it is nil.
+	htmlTags []string
+	// emojiPolicy maps the descriptive text files in which emoji and decorative
+	// symbols are reported to the severity of the reports.
+	emojiPolicy map[string]severity
+	// titleClaims match the promotional and performance claims that the title
+	// must not contain. The title isn't checked for claims if it is nil.
+	titleClaims []*regexp.Regexp
+	// titleClaimSeverity is the severity of the claims found in the title.
+	titleClaimSeverity severity
+}
+
+// profiles contains the supported store profiles by their `-profile` names.
+var profiles = map[string]*storeProfile{
+	"play": {
+		textLimits: map[string]int{
+			"title.txt":             30,
+			"short_description.txt": 80,
+			"full_description.txt":  4000,
+		},
+		changelogMaxLength: 500,
+		textMinLengths: map[string]int{
+			"title.txt":             1,
+			"short_description.txt": 1,
+			"full_description.txt":  1,
+		},
+		screenshots: map[string]screenshotSpec{
+			"phoneScreenshots":     phoneScreenshotSpec,
+			"sevenInchScreenshots": tabletScreenshotSpec,
+			"tenInchScreenshots":   tabletScreenshotSpec,
+			"tvScreenshots":        tvScreenshotSpec,
+			"wearScreenshots":      wearScreenshotSpec,
+		},
+		strictGraphics: true,
+		imageMaxBytes: map[string]int64{
+			"icon":           1 << 20,
+			"featureGraphic": 15 << 20,
+			"promoGraphic":   15 << 20,
+			"tvBanner":       15 << 20,
+		},
+		htmlTags: []string{"b", "br", "i", "li", "ol", "u", "ul"},
+		emojiPolicy: map[string]severity{
+			"title.txt":             severityError,
+			"short_description.txt": severityWarning,
+		},
+		titleClaims: []*regexp.Regexp{
+			regexp.MustCompile(`(?i)\b(free|best|sale)\b`),
+			regexp.MustCompile(`(?i)(#|\bno\.?\s?|\bnumber\s)1\b`),
+			regexp.MustCompile(`(?i)[$€£¥₹]\s?\d+([.,]\d+)?|\b\d+([.,]\d+)?\s?(\$|€|£|usd|eur|gbp)`),
+		},
+		titleClaimSeverity: severityWarning,
+	},
+	// F-Droid doesn't limit the title or the graphics, and renders a subset of
+	// HTML in the full description.
+	"fdroid": {
+		textLimits: map[string]int{
+			"short_description.txt": 80,
+			"full_description.txt":  4000,
+		},
+		changelogMaxLength: 500,
+		textMinLengths: map[string]int{
+			"short_description.txt": 1,
+			"full_description.txt":  1,
+		},
+		screenshots: map[string]screenshotSpec{},
+		htmlTags: []string{
+			"a", "b", "big", "blockquote", "br", "cite", "em", "i", "li", "ol",
+			"p", "small", "strike", "strong", "sub", "sup", "tt", "u", "ul",
+		},
+	},
+}
+
+// activeProfile is the store profile selected by `-profile`.
+var activeProfile = profiles["play"]
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+// reportIssue is an error or a warning in the JSON report.
+type reportIssue struct {
+	File     string `json:"file,omitempty"`
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// localeResult summarises the validation results of a locale.
+type localeResult struct {
+	Locale     string `json:"locale"`
+	Errors     int    `json:"errors"`
+	Warnings   int    `json:"warnings"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// report is the JSON representation of the validation results.
+type report struct {
+	Errors   int            `json:"errors"`
+	Warnings int            `json:"warnings"`
+	Issues   []reportIssue  `json:"issues"`
+	Locales  []localeResult `json:"locales,omitempty"`
+}
+
+func newReport(errs []error) *report {
+	r := &report{Issues: make([]reportIssue, 0, len(errs))}
+	for _, err := range errs {
+		issue := reportIssue{Rule: "io", Severity: "error", Message: err.Error()}
+		if ve, ok := err.(*validationError); ok {
+			issue.File = ve.File
+			issue.Rule = ve.Rule
+			issue.Message = ve.Err.Error()
+			if ve.Severity == severityWarning {
+				issue.Severity = "warning"
+			}
+		}
+
+		if issue.Severity == "warning" {
+			r.Warnings++
+		} else {
+			r.Errors++
+		}
+
+		r.Issues = append(r.Issues, issue)
+	}
+
+	return r
+}
+
+// runResult is the single-line summary printed at the end of every run.
+type runResult struct {
+	Errors     int   `json:"errors"`
+	Warnings   int   `json:"warnings"`
+	Locales    int   `json:"locales"`
+	DurationMs int64 `json:"duration_ms"`
+}
+
+// printRunResult prints the single-line machine-readable summary of the run to
+// stderr, so that it doesn't interfere with the JSON report on stdout.
+func printRunResult(r *report, locales int, duration time.Duration) {
+	content, _ := json.Marshal(runResult{
+		Errors:     r.Errors,
+		Warnings:   r.Warnings,
+		Locales:    locales,
+		DurationMs: duration.Milliseconds(),
+	})
+
+	fmt.Fprintln(os.Stderr, string(content))
+}
+
+// readReport reads a JSON report written by a previous run.
+func readReport(path string) (*report, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &report{}
+	if err := json.Unmarshal(content, r); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// printTextReport prints the human-readable validation results followed by a
+// per-locale summary. Identical issues reported for several files, e.g. the
+// same problem in many locales, are collapsed into a single entry listing all
+// files.
+func printTextReport(errs []error, locales []localeResult) {
+	r := newReport(errs)
+	summary := fmt.Sprint("found ", r.Errors, " errors and ", r.Warnings, " warnings!")
+	fmt.Println(colorize(os.Stdout, colorBold, summary))
+
+	type issueKey struct{ rule, severity, message string }
+	groups := make(map[issueKey][]string)
+	keys := make([]issueKey, 0)
+	for i, err := range errs {
+		if ve, ok := err.(*validationError); ok && useFileAnnotations {
+			ve.annotateGitHubFile()
+		}
+
+		issue := r.Issues[i]
+		if issue.File == "" {
+			fmt.Fprintln(os.Stderr, colorize(os.Stderr, colorRed, err.Error()))
+			continue
+		}
+
+		k := issueKey{issue.Rule, issue.Severity, issue.Message}
+		if _, ok := groups[k]; !ok {
+			keys = append(keys, k)
+		}
+
+		groups[k] = append(groups[k], issue.File)
+	}
+
+	for _, k := range keys {
+		files := groups[k]
+		message := colorize(os.Stderr, colorRed, k.message)
+		if k.severity == "warning" {
+			message = colorize(os.Stderr, colorYellow, "warning: "+k.message)
+		}
+
+		if len(files) == 1 {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", colorize(os.Stderr, colorBold, files[0]), message)
+			continue
+		}
+
+		fmt.Fprintf(os.Stderr, "%s (%d files):\n", message, len(files))
+		for _, f := range files {
+			fmt.Fprintf(os.Stderr, "    %s\n", colorize(os.Stderr, colorBold, f))
+		}
+	}
+
+	if len(locales) == 0 {
+		return
+	}
+
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "LOCALE\tSTATUS\tERRORS\tWARNINGS\tTIME")
+	for _, l := range locales {
+		status := colorize(os.Stdout, colorGreen, "pass")
+		if l.Errors > 0 {
+			status = colorize(os.Stdout, colorRed, "fail")
+		}
+
+		const rowFmt = "%s\t%s\t%d\t%d\t%dms\n"
+		fmt.Fprintf(w, rowFmt, l.Locale, status, l.Errors, l.Warnings, l.DurationMs)
+	}
+
+	w.Flush()
+}
+
+// printJSONReport prints the validation results as a JSON report to stdout.
+func printJSONReport(errs []error, locales []localeResult) {
+	if useFileAnnotations {
+		for _, err := range errs {
+			if ve, ok := err.(*validationError); ok {
+				ve.annotateGitHubFile()
+			}
+		}
+	}
+
+	r := newReport(errs)
+	r.Locales = locales
+	printJSON(r)
+}
+
+// printJSON prints `v` as indented JSON to stdout.
+func printJSON(v interface{}) {
+	content, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		const errFmt = "failed to encode JSON: %s\n"
+		fmt.Fprintf(os.Stderr, errFmt, err)
+		os.Exit(1)
+	}
+
+	fmt.Println(string(content))
+}
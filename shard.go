@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// parseShard parses a shard specification of the form "i/n", where `i` is the
+// 1-based index of the shard and `n` is the total number of shards.
+func parseShard(s string) (index, count int, err error) {
+	if _, err := fmt.Sscanf(s, "%d/%d", &index, &count); err != nil {
+		return 0, 0, fmt.Errorf("expected the form i/n")
+	}
+
+	if count < 1 || index < 1 || index > count {
+		return 0, 0, fmt.Errorf("expected 1 <= i <= n")
+	}
+
+	return index, count, nil
+}
+
+// inShard reports whether the i-th (0-based) item of a sorted list belongs to
+// the current shard. Items are distributed among shards in a round robin.
+func inShard(i int) bool {
+	return shardCount <= 1 || i%shardCount == shardIndex-1
+}
+
+// mergeReportsCommand combines the JSON reports of several (sharded) runs into
+// a single report. It exits with a non-zero status if the combined report has
+// errors.
+func mergeReportsCommand(args []string) {
+	fs := flag.NewFlagSet("merge-reports", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: merge-reports report.json...")
+	}
+
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	errs := make([]error, 0)
+	locales := make([]localeResult, 0)
+	for _, path := range fs.Args() {
+		r, err := readReport(path)
+		if err != nil {
+			const errFmt = "failed to read report %q: %s\n"
+			fmt.Fprintf(os.Stderr, errFmt, path, err)
+			os.Exit(1)
+		}
+
+		for _, i := range r.Issues {
+			errs = append(errs, i.toError())
+		}
+
+		locales = append(locales, r.Locales...)
+	}
+
+	sort.Slice(locales, func(i, j int) bool { return locales[i].Locale < locales[j].Locale })
+	if outputFormat == "json" {
+		printJSONReport(errs, locales)
+	} else {
+		printTextReport(errs, locales)
+	}
+
+	if newReport(errs).Errors > 0 {
+		os.Exit(1)
+	}
+}
+
+// toError converts the issue back to the error that it was created from.
+func (i reportIssue) toError() error {
+	if i.File == "" && i.Rule == "io" {
+		return errors.New(i.Message)
+	}
+
+	s := severityError
+	if i.Severity == "warning" {
+		s = severityWarning
+	}
+
+	return &validationError{
+		File:     i.File,
+		Rule:     i.Rule,
+		Err:      errors.New(i.Message),
+		Severity: s,
+	}
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// spellcheckConfig declares the optional spellcheck of the descriptive texts
+// and the changelogs. It is disabled unless a dictionary applies to a locale.
+type spellcheckConfig struct {
+	Dictionaries []dictionaryRule `json:"dictionaries"`
+	Words        []string         `json:"words"`
+	WordsFile    string           `json:"wordsFile"`
+	Severity     string           `json:"severity"`
+
+	words    map[string]bool
+	severity severity
+}
+
+// dictionaryRule selects the hunspell dictionary at `Path`, without the `.dic`
+// and `.aff` extensions, for the `Locales` (glob patterns) it lists, or for all
+// locales if it doesn't list any.
+type dictionaryRule struct {
+	Locales []string `json:"locales"`
+	Path    string   `json:"path"`
+
+	dict map[string]bool
+}
+
+// compile loads the dictionaries and the custom words of the spellcheck config.
+// Relative paths are resolved against `configDir`.
+func (s *spellcheckConfig) compile(configDir string) error {
+	resolve := func(p string) string {
+		if filepath.IsAbs(p) {
+			return p
+		}
+
+		return filepath.Join(configDir, p)
+	}
+
+	var err error
+	if s.severity, err = parseSeverity(s.Severity); err != nil {
+		return fmt.Errorf("severity: %w", err)
+	}
+
+	s.words = make(map[string]bool)
+	for _, w := range s.Words {
+		s.words[strings.ToLower(w)] = true
+	}
+
+	if s.WordsFile != "" {
+		content, err := ioutil.ReadFile(resolve(s.WordsFile))
+		if err != nil {
+			return fmt.Errorf("wordsFile: %w", err)
+		}
+
+		for _, line := range strings.Split(string(content), "\n") {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+				s.words[strings.ToLower(line)] = true
+			}
+		}
+	}
+
+	for i := range s.Dictionaries {
+		dict, err := loadHunspellDictionary(resolve(s.Dictionaries[i].Path))
+		if err != nil {
+			return fmt.Errorf("dictionaries[%d]: %w", i, err)
+		}
+
+		s.Dictionaries[i].dict = dict
+	}
+
+	return nil
+}
+
+// dictionary returns the dictionary of the first rule that applies to the
+// given locale, or nil if none applies.
+func (s *spellcheckConfig) dictionary(locale string) map[string]bool {
+	for _, rule := range s.Dictionaries {
+		if len(rule.Locales) == 0 || matchesAnyGlob(rule.Locales, locale) {
+			return rule.dict
+		}
+	}
+
+	return nil
+}
+
+var spellcheckURLRegexp = regexp.MustCompile(`\S+://\S+|www\.\S+|\S+@\S+\.\S+`)
+
+// checkSpelling reports the words in the text file at `filePath` that neither
+// the dictionary of its locale nor the custom word lists contain. Acronyms and
+// words with digits are left out.
+func checkSpelling(filePath string) []error {
+	if cfg.Spellcheck == nil {
+		return nil
+	}
+
+	dict := cfg.Spellcheck.dictionary(filepath.Base(layout.localePath(filePath)))
+	if dict == nil {
+		return nil
+	}
+
+	content, err := readFile(filePath)
+	if err != nil {
+		return nil // already reported by the caller
+	}
+
+	text := spellcheckURLRegexp.ReplaceAllString(string(content), " ")
+	found := make([]string, 0)
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\'' && r != '’'
+	}) {
+		word = strings.Trim(word, "'’")
+		if word == "" || strings.IndexFunc(word, unicode.IsDigit) >= 0 || strings.ToUpper(word) == word {
+			continue
+		}
+
+		lower := strings.ToLower(word)
+		if dict[lower] || cfg.Spellcheck.words[lower] || dict[strings.TrimSuffix(strings.TrimSuffix(lower, "'s"), "’s")] {
+			continue
+		}
+
+		if q := strconv.Quote(word); !containsString(found, q) {
+			found = append(found, q)
+		}
+	}
+
+	if len(found) > 0 {
+		const errFmt = "possible misspellings: %s"
+		return []error{&validationError{
+			File:     filePath,
+			Rule:     "spelling",
+			Err:      fmt.Errorf(errFmt, strings.Join(found, ", ")),
+			Severity: cfg.Spellcheck.severity,
+		}}
+	}
+
+	return nil
+}
+
+// affixRule is a prefix or a suffix rule of a hunspell affix file.
+type affixRule struct {
+	strip     string
+	add       string
+	condition *regexp.Regexp
+}
+
+// affixClass is the set of the prefix or the suffix rules sharing a flag.
+type affixClass struct {
+	prefix bool
+	cross  bool
+	rules  []affixRule
+}
+
+// loadHunspellDictionary reads the hunspell dictionary at `path` + `.dic` and
+// its affix file at `path` + `.aff`, and returns the lower case forms of all of
+// its words. Only the prefix and the suffix rules of the affix file are
+// supported; compounding and the other options are ignored.
+func loadHunspellDictionary(path string) (map[string]bool, error) {
+	classes, flagMode, err := parseAffixFile(path + ".aff")
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path + ".dic")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	words := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for first := true; scanner.Scan(); first = false {
+		line := strings.TrimSpace(scanner.Text())
+		if first || line == "" || strings.HasPrefix(line, "#") {
+			continue // the first line is the word count
+		}
+
+		if i := strings.IndexAny(line, " \t"); i >= 0 {
+			line = line[:i] // morphological fields
+		}
+
+		word, flags := line, ""
+		if i := strings.Index(line, "/"); i > 0 {
+			word, flags = line[:i], line[i+1:]
+		}
+
+		words[strings.ToLower(word)] = true
+		for _, form := range expandAffixes(word, parseAffixFlags(flags, flagMode), classes) {
+			words[strings.ToLower(form)] = true
+		}
+	}
+
+	return words, scanner.Err()
+}
+
+// parseAffixFile parses the prefix and the suffix rules of the hunspell affix
+// file at `path`, keyed by their flags, and returns its `FLAG` mode.
+func parseAffixFile(path string) (map[string]*affixClass, string, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	classes := make(map[string]*affixClass)
+	flagMode := ""
+	for i, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch {
+		case fields[0] == "FLAG" && len(fields) > 1:
+			flagMode = fields[1]
+		case (fields[0] == "PFX" || fields[0] == "SFX") && len(fields) == 4 && (fields[2] == "Y" || fields[2] == "N"):
+			classes[fields[1]] = &affixClass{prefix: fields[0] == "PFX", cross: fields[2] == "Y"}
+		case (fields[0] == "PFX" || fields[0] == "SFX") && len(fields) >= 5:
+			class, ok := classes[fields[1]]
+			if !ok {
+				return nil, "", fmt.Errorf("%s:%d: affix rule without header", path, i+1)
+			}
+
+			rule, err := newAffixRule(fields[2], fields[3], fields[4], class.prefix)
+			if err != nil {
+				return nil, "", fmt.Errorf("%s:%d: %w", path, i+1, err)
+			}
+
+			class.rules = append(class.rules, rule)
+		}
+	}
+
+	return classes, flagMode, nil
+}
+
+// newAffixRule returns the affix rule that strips `strip` from and adds `add`
+// to the words matching `condition`.
+func newAffixRule(strip, add, condition string, prefix bool) (affixRule, error) {
+	if strip == "0" {
+		strip = ""
+	}
+
+	if i := strings.Index(add, "/"); i >= 0 {
+		add = add[:i] // continuation classes aren't supported
+	}
+
+	if add == "0" {
+		add = ""
+	}
+
+	pattern := condition + "$"
+	if prefix {
+		pattern = "^" + condition
+	}
+
+	re, err := regexp.Compile(pattern)
+	return affixRule{strip: strip, add: add, condition: re}, err
+}
+
+// parseAffixFlags splits the flags of a dictionary word according to the
+// `FLAG` mode of the affix file.
+func parseAffixFlags(flags, mode string) []string {
+	switch mode {
+	case "long":
+		parsed := make([]string, 0, len(flags)/2)
+		for i := 0; i+1 < len(flags); i += 2 {
+			parsed = append(parsed, flags[i:i+2])
+		}
+
+		return parsed
+	case "num":
+		return strings.Split(flags, ",")
+	}
+
+	parsed := make([]string, 0, len(flags))
+	for _, r := range flags {
+		parsed = append(parsed, string(r))
+	}
+
+	return parsed
+}
+
+// expandAffixes returns the forms of `word` that the affix classes of `flags`
+// produce, including the combinations of cross product prefixes and suffixes.
+func expandAffixes(word string, flags []string, classes map[string]*affixClass) []string {
+	forms := make([]string, 0)
+	suffixed := make([]string, 0)
+	for _, flag := range flags {
+		class, ok := classes[flag]
+		if !ok || class.prefix {
+			continue
+		}
+
+		for _, rule := range class.rules {
+			if rule.condition.MatchString(word) && strings.HasSuffix(word, rule.strip) {
+				form := strings.TrimSuffix(word, rule.strip) + rule.add
+				forms = append(forms, form)
+				if class.cross {
+					suffixed = append(suffixed, form)
+				}
+			}
+		}
+	}
+
+	for _, flag := range flags {
+		class, ok := classes[flag]
+		if !ok || !class.prefix {
+			continue
+		}
+
+		for _, rule := range class.rules {
+			if rule.condition.MatchString(word) && strings.HasPrefix(word, rule.strip) {
+				forms = append(forms, rule.add+strings.TrimPrefix(word, rule.strip))
+				if !class.cross {
+					continue
+				}
+
+				for _, form := range suffixed {
+					forms = append(forms, rule.add+strings.TrimPrefix(form, rule.strip))
+				}
+			}
+		}
+	}
+
+	return forms
+}
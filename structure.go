@@ -0,0 +1,308 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/agnivade/levenshtein"
+)
+
+// imageExtensions are the extensions of the image formats that supply uploads.
+var imageExtensions = []string{".png", ".jpg", ".jpeg"}
+
+// imageNames are the fastlane names of the graphics other than screenshots.
+var imageNames = []string{"icon", "featureGraphic", "promoGraphic", "tvBanner"}
+
+// screenshotDirs are the fastlane names of the screenshot directories.
+var screenshotDirs = []string{"phoneScreenshots", "sevenInchScreenshots", "tenInchScreenshots", "tvScreenshots", "wearScreenshots"}
+
+// changelogNameRegexp matches the names of the changelogs that supply uploads:
+// a positive version code or `default` with the `.txt` extension.
+var changelogNameRegexp = regexp.MustCompile(`^([1-9]\d*|default)\.txt$`)
+
+// screenshotNameRegexp is the `-screenshot-name-pattern` that the file names
+// of screenshots must match. It is nil unless the flag is set.
+var screenshotNameRegexp *regexp.Regexp
+
+// screenshotNumberRegexp matches the number that a screenshot's file name
+// starts with, e.g. `10` in `10_settings.png`.
+var screenshotNumberRegexp = regexp.MustCompile(`^\d+`)
+
+// localeEntries returns the names of the files and the directories that
+// supply recognises in the locale at `localePath`, and in its images directory.
+// It also returns the names of the directories in the images directory.
+func localeEntries(localePath string) (names, imagesNames, imageDirs []string) {
+	names = []string{layout.imagesDir}
+	for _, name := range []string{"title.txt", "short_description.txt", "full_description.txt", "video.txt"} {
+		names = append(names, filepath.Base(layout.textPath(localePath, name)))
+	}
+
+	for _, spec := range cfg.TextFiles {
+		names = append(names, spec.Name)
+	}
+
+	if layout.releaseNotesDir == "" {
+		names = append(names, "changelogs")
+	}
+
+	if layout.imageDirs != nil {
+		imageDirs = make([]string, 0, len(layout.imageDirs))
+		for dir := range layout.imageDirs {
+			imageDirs = append(imageDirs, dir)
+		}
+
+		return names, imageDirs, imageDirs
+	}
+
+	imagesNames = append([]string{}, screenshotDirs...)
+	for _, name := range imageNames {
+		for _, ext := range imageExtensions {
+			imagesNames = append(imagesNames, name+ext)
+		}
+	}
+
+	return names, imagesNames, screenshotDirs
+}
+
+// checkStructure checks that the locale at `localePath` only contains the files
+// and the directories that supply uploads, if `-strict` is set. Otherwise,
+// typos like `tittle.txt` silently result in missing store content.
+func checkStructure(localePath string) []error {
+	if !strictStructure {
+		return nil
+	}
+
+	names, imagesNames, imageDirs := localeEntries(localePath)
+	errs := checkUnknownEntries(localePath, names, func(name string, isDir bool) bool {
+		return containsString(names, name) && isDir == (name == layout.imagesDir || name == "changelogs")
+	})
+
+	imagesPath := filepath.Join(localePath, layout.imagesDir)
+	errs = append(errs, checkUnknownEntries(imagesPath, imagesNames, func(name string, isDir bool) bool {
+		return containsString(imagesNames, name) && isDir == containsString(imageDirs, name)
+	})...)
+
+	for _, dir := range imageDirs {
+		errs = append(errs, checkUnknownEntries(filepath.Join(imagesPath, dir), nil, func(name string, isDir bool) bool {
+			return !isDir && containsString(imageExtensions, strings.ToLower(filepath.Ext(name)))
+		})...)
+	}
+
+	return append(errs, checkUnknownEntries(layout.changelogsPath(localePath), nil, func(name string, isDir bool) bool {
+		if layout.releaseNotesDir != "" {
+			return !isDir && filepath.Ext(name) == ".txt"
+		}
+
+		return !isDir // the names are checked by checkChangelogs
+	})...)
+}
+
+// checkImageEntries reports the entries of the images directory that supply
+// ignores, so that artwork that never reaches the store gets noticed. Names
+// close to those of the graphics or the screenshot directories, e.g.
+// `featureGrpahic.png` or `phoneScreenshot`, are errors with a suggestion, and
+// the others are warnings. It does nothing with `-strict`, which reports them
+// as unknown files.
+func checkImageEntries(localePath string) []error {
+	if strictStructure || layout.imageDirs != nil {
+		return nil
+	}
+
+	imagesPath := filepath.Join(localePath, layout.imagesDir)
+	files, err := readDir(imagesPath)
+	if err != nil {
+		return nil // already reported by checkImages
+	}
+
+	_, imagesNames, _ := localeEntries(localePath)
+	errs := make([]error, 0)
+	for _, f := range files {
+		if !isUnusedImage(f.Name()) {
+			continue // reported by checkFileCase if the case differs
+		}
+
+		err := &validationError{
+			File:     filepath.Join(imagesPath, f.Name()),
+			Rule:     "unused-image",
+			Err:      fmt.Errorf("supply ignores this file: only icon, featureGraphic, promoGraphic, tvBanner and the screenshot directories are uploaded"),
+			Severity: severityWarning,
+		}
+
+		for _, name := range imagesNames {
+			if levenshtein.ComputeDistance(strings.ToLower(f.Name()), strings.ToLower(name)) <= 2 {
+				const errFmt = "supply ignores this name: did you mean %q?"
+				err.Rule, err.Err, err.Severity = "image-name", fmt.Errorf(errFmt, name), severityError
+				break
+			}
+		}
+
+		errs = append(errs, err)
+	}
+
+	return errs
+}
+
+// checkFileCase checks that the files and the directories that supply
+// recognises ignoring case have their exact names, e.g. `featureGraphic.png`
+// rather than `Featuregraphic.png`. Case-insensitive file systems, e.g. on
+// macOS, hide such mistakes until supply runs on Linux.
+func checkFileCase(localePath string) []error {
+	names, imagesNames, _ := localeEntries(localePath)
+	errs := checkEntryCase(localePath, names)
+	return append(errs, checkEntryCase(filepath.Join(localePath, layout.imagesDir), imagesNames)...)
+}
+
+// checkEntryCase reports the entries of `dirPath` that only differ from one of
+// the `names` in case.
+func checkEntryCase(dirPath string, names []string) []error {
+	files, err := readDir(dirPath)
+	if err != nil {
+		return nil // already reported by the checks
+	}
+
+	errs := make([]error, 0)
+	for _, f := range files {
+		if name := canonicalName(f.Name(), names); name != "" {
+			const errFmt = "name must be %q: supply is case-sensitive"
+			errs = append(errs, &validationError{
+				File: filepath.Join(dirPath, f.Name()),
+				Rule: "file-case",
+				Err:  fmt.Errorf(errFmt, name),
+			})
+		}
+	}
+
+	return errs
+}
+
+// isUnusedImage reports whether checkImageEntries reports the entry `name` of
+// the images directory as one that supply ignores. The image checks skip such
+// entries, since they may not even be images, e.g. `notes.txt`.
+func isUnusedImage(name string) bool {
+	if strictStructure || layout.imageDirs != nil || name == ".gitignore" || name == ".validateignore" {
+		return false
+	}
+
+	_, imagesNames, _ := localeEntries("")
+	return !containsString(imagesNames, name) && canonicalName(name, imagesNames) == ""
+}
+
+// canonicalName returns the one of the `names` that only differs from `name`
+// in case, or an empty string if there is none.
+func canonicalName(name string, names []string) string {
+	for _, n := range names {
+		if n != name && strings.EqualFold(n, name) {
+			return n
+		}
+	}
+
+	return ""
+}
+
+// checkUnknownEntries reports the entries of `dirPath` that `isKnown` doesn't
+// recognise. If an entry is similar to one of the known `names`, it is
+// suggested as an alternative.
+func checkUnknownEntries(dirPath string, names []string, isKnown func(name string, isDir bool) bool) []error {
+	files, err := readDir(dirPath)
+	if err != nil {
+		return nil // already reported by the checks
+	}
+
+	errs := make([]error, 0)
+	for _, f := range files {
+		if f.Name() == ".gitignore" || f.Name() == ".validateignore" || isKnown(f.Name(), f.IsDir()) {
+			continue
+		}
+
+		if canonicalName(f.Name(), names) != "" {
+			continue // reported by checkFileCase
+		}
+
+		msg := "supply doesn't use this file"
+		if f.IsDir() {
+			msg = "supply doesn't use this directory"
+		}
+
+		for _, name := range names {
+			if name != f.Name() && levenshtein.ComputeDistance(strings.ToLower(f.Name()), strings.ToLower(name)) <= 2 {
+				msg = fmt.Sprintf("%s: did you mean %q?", msg, name)
+				break
+			}
+		}
+
+		errs = append(errs, &validationError{
+			File: filepath.Join(dirPath, f.Name()),
+			Rule: "unknown-file",
+			Err:  errors.New(msg),
+		})
+	}
+
+	return errs
+}
+
+// checkScreenshotNames checks the file names of the screenshots in
+// `screenshotsPath` against `-screenshot-name-pattern`. supply uploads the
+// screenshots in the lexicographic order of their names, so it also warns if
+// numbered names, e.g. `2.png` and `10.png`, would upload out of their
+// numeric order, and suggests zero-padded names.
+func checkScreenshotNames(screenshotsPath string, names []string) []error {
+	errs := make([]error, 0)
+	if screenshotNameRegexp != nil {
+		for _, name := range names {
+			if !screenshotNameRegexp.MatchString(name) {
+				const errFmt = "file name doesn't match the screenshot name pattern %q"
+				errs = append(errs, &validationError{
+					File: filepath.Join(screenshotsPath, name),
+					Rule: "screenshot-name",
+					Err:  fmt.Errorf(errFmt, screenshotNameRegexp),
+				})
+			}
+		}
+	}
+
+	numbered := make([]string, 0, len(names))
+	width := 0
+	for _, name := range names {
+		if n := screenshotNumberRegexp.FindString(name); n != "" {
+			numbered = append(numbered, name)
+			if len(n) > width {
+				width = len(n)
+			}
+		}
+	}
+
+	sort.Strings(numbered)
+	number := func(name string) string {
+		return strings.TrimLeft(screenshotNumberRegexp.FindString(name), "0")
+	}
+
+	for i := 1; i < len(numbered); i++ {
+		a, b := number(numbered[i-1]), number(numbered[i])
+		if len(a) < len(b) || len(a) == len(b) && a <= b {
+			continue
+		}
+
+		renames := make([]string, 0)
+		for _, name := range numbered {
+			if n := screenshotNumberRegexp.FindString(name); len(n) < width {
+				renames = append(renames, fmt.Sprintf("%s to %s%s", name, strings.Repeat("0", width-len(n)), name))
+			}
+		}
+
+		const errFmt = "supply uploads %s before %s: rename %s"
+		errs = append(errs, &validationError{
+			File:     screenshotsPath,
+			Rule:     "screenshot-order",
+			Err:      fmt.Errorf(errFmt, numbered[i-1], numbered[i], strings.Join(renames, ", ")),
+			Severity: severityWarning,
+		})
+
+		break
+	}
+
+	return errs
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// symlinkInfo describes the target of a symlink under the name of the symlink.
+type symlinkInfo struct {
+	os.FileInfo
+	name string
+}
+
+func (i symlinkInfo) Name() string { return i.name }
+
+// isSymlink reports whether the file at `path` is a symlink.
+func isSymlink(path string) bool {
+	info, err := os.Lstat(path)
+	return err == nil && info.Mode()&os.ModeSymlink != 0
+}
+
+// checkSymlinks checks the symlinks in the locale at `localePath` according to
+// the `-symlinks` policy. With `follow`, it reports broken symlinks and
+// symlinks to their own parent directories. With `error`, it reports all
+// symlinks. The errors refer to the symlinks rather than their targets.
+func checkSymlinks(localePath string) []error {
+	if symlinkPolicy == "skip" {
+		return nil
+	}
+
+	if _, _, ok := mountedPath(localePath); ok {
+		return nil // archives can't have symlinks
+	}
+
+	realPath, err := filepath.EvalSymlinks(localePath)
+	if err != nil {
+		return nil // already reported by the caller
+	}
+
+	return checkSymlinksInDir(localePath, []string{realPath})
+}
+
+// checkSymlinksInDir checks the symlinks in `dirPath` and its subdirectories.
+// `ancestors` contains the real paths of `dirPath` and its parents to detect
+// loops.
+func checkSymlinksInDir(dirPath string, ancestors []string) []error {
+	files, err := ioutil.ReadDir(dirPath)
+	if err != nil {
+		return nil // already reported by the checks
+	}
+
+	errs := make([]error, 0)
+	for _, f := range files {
+		filePath := filepath.Join(dirPath, f.Name())
+		if isExcluded(filePath) {
+			continue
+		}
+
+		realPath := filePath
+		if f.Mode()&os.ModeSymlink != 0 {
+			target, _ := os.Readlink(filePath)
+			if symlinkPolicy == "error" {
+				const errFmt = "symlinks aren't allowed: points to %q"
+				errs = append(errs, &validationError{
+					File: filePath,
+					Rule: "symlink",
+					Err:  fmt.Errorf(errFmt, target),
+				})
+				continue
+			}
+
+			if realPath, err = filepath.EvalSymlinks(filePath); err != nil {
+				const errFmt = "broken symlink: points to %q"
+				errs = append(errs, &validationError{
+					File: filePath,
+					Rule: "symlink",
+					Err:  fmt.Errorf(errFmt, target),
+				})
+				continue
+			}
+
+			if containsString(ancestors, realPath) {
+				const errFmt = "symlink loop: points to its parent directory %q"
+				errs = append(errs, &validationError{
+					File: filePath,
+					Rule: "symlink",
+					Err:  fmt.Errorf(errFmt, target),
+				})
+				continue
+			}
+
+			if f, err = os.Stat(filePath); err != nil {
+				continue
+			}
+		}
+
+		if f.IsDir() {
+			errs = append(errs, checkSymlinksInDir(filePath, append(ancestors, realPath))...)
+		}
+	}
+
+	return errs
+}
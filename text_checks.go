@@ -0,0 +1,1306 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	repeatedPunctRegexp = regexp.MustCompile(`[!?]{2,}|,{2,}|;{2,}|:{2,}|\*{2,}|~{2,}|\.{4,}`)
+	htmlTagRegexp       = regexp.MustCompile(`</?([a-zA-Z][a-zA-Z0-9]*)(\s[^<>]*)?/?>`)
+	conflictRegexp      = regexp.MustCompile(`(?m)^(?:<{7}|>{7}|\|{7})(?:[ \t].*)?\r?$|^={7}\r?$`)
+	markdownRegexp      = regexp.MustCompile("(?m)^#{1,6}\\s+\\S+|\\*\\*[^*\\n]+\\*\\*|__[^_\\n]+__|\\[[^\\]\\n]+\\]\\([^)\\s]+\\)|`[^`\\n]+`")
+)
+
+// checkPlainText checks that the text file at `filePath`, e.g. a changelog,
+// doesn't contain HTML tags, Markdown syntax or only emoji. Play renders release
+// notes as plain text, so any markup shows literally.
+func checkPlainText(filePath string) []error {
+	content, err := readFile(filePath)
+	if err != nil {
+		return nil // already reported by the caller
+	}
+
+	text := string(content)
+	errs := make([]error, 0)
+	if tag := htmlTagRegexp.FindString(text); tag != "" {
+		const errFmt = "content must be plain text: found HTML tag %q"
+		errs = append(errs, &validationError{
+			File: filePath,
+			Rule: "plain-text",
+			Err:  fmt.Errorf(errFmt, tag),
+		})
+	}
+
+	if md := markdownRegexp.FindString(text); md != "" {
+		const errFmt = "content must be plain text: found Markdown syntax %q"
+		errs = append(errs, &validationError{
+			File: filePath,
+			Rule: "plain-text",
+			Err:  fmt.Errorf(errFmt, md),
+		})
+	}
+
+	if isEmojiOnly(text) {
+		errs = append(errs, &validationError{
+			File: filePath,
+			Rule: "plain-text",
+			Err:  fmt.Errorf("content must be plain text: found only emoji"),
+		})
+	}
+
+	return errs
+}
+
+// checkShouting warns about fully capitalised words, runs of repeated
+// punctuation and excessive exclamation marks in the title and the short
+// description. Play rejects listings that use them to grab attention, e.g.
+// "BEST FREE APP!!!".
+func checkShouting(localePath string) []error {
+	errs := make([]error, 0)
+	for _, file := range []string{"title.txt", "short_description.txt"} {
+		filePath := layout.textPath(localePath, file)
+		content, err := readFile(filePath)
+		if err != nil {
+			continue // already reported by checkDescriptiveTexts
+		}
+
+		text := string(content)
+		if words := findAllCapsWords(text); len(words) > 0 {
+			const errFmt = "avoid fully capitalised words: found %s"
+			errs = append(errs, &validationError{
+				File:     filePath,
+				Rule:     "all-caps",
+				Err:      fmt.Errorf(errFmt, strings.Join(words, ", ")),
+				Severity: severityWarning,
+			})
+		}
+
+		if punct := repeatedPunctRegexp.FindString(text); punct != "" {
+			const errFmt = "avoid repeated punctuation: found %q"
+			errs = append(errs, &validationError{
+				File:     filePath,
+				Rule:     "repeated-punctuation",
+				Err:      fmt.Errorf(errFmt, punct),
+				Severity: severityWarning,
+			})
+		}
+
+		const maxExclamationMarks = 1
+		if count := strings.Count(text, "!") + strings.Count(text, "！"); count > maxExclamationMarks {
+			const errFmt = "too many exclamation marks: expected<=%d, got=%d"
+			errs = append(errs, &validationError{
+				File:     filePath,
+				Rule:     "exclamation-marks",
+				Err:      fmt.Errorf(errFmt, maxExclamationMarks, count),
+				Severity: severityWarning,
+			})
+		}
+	}
+
+	return errs
+}
+
+// findAllCapsWords returns the quoted words in `text` that are written in
+// capital letters only. Words shorter than 4 letters are left out, since they
+// are usually acronyms, e.g. "GPS" or "PDF".
+func findAllCapsWords(text string) []string {
+	const minLength = 4
+	found := make([]string, 0)
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool { return !unicode.IsLetter(r) }) {
+		if utf8.RuneCountInString(word) < minLength || strings.ToUpper(word) != word || strings.ToLower(word) == word {
+			continue
+		}
+
+		if q := strconv.Quote(word); !containsString(found, q) {
+			found = append(found, q)
+		}
+	}
+
+	return found
+}
+
+// checkKeywordStuffing warns if a word makes up more than `keywordDensity` of
+// the full description at `filePath`, or if the description ends with a block
+// of comma-separated keywords. Play's metadata policy prohibits both.
+func checkKeywordStuffing(filePath string) []error {
+	content, err := readFile(filePath)
+	if err != nil {
+		return nil // already reported by checkDescriptiveTexts
+	}
+
+	text := string(content)
+	errs := make([]error, 0)
+	if word, density := densestKeyword(text); keywordDensity > 0 && density > keywordDensity {
+		const errFmt = "keyword %q makes up %.1f%% of the words: expected at most %.1f%%"
+		errs = append(errs, &validationError{
+			File:     filePath,
+			Rule:     "keyword-stuffing",
+			Err:      fmt.Errorf(errFmt, word, density*100, keywordDensity*100),
+			Severity: severityWarning,
+		})
+	}
+
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	if count := keywordBlockSize(lines[len(lines)-1]); count > 0 {
+		const errFmt = "description ends with a block of %d comma-separated keywords"
+		errs = append(errs, &validationError{
+			File:     filePath,
+			Rule:     "keyword-stuffing",
+			Err:      fmt.Errorf(errFmt, count),
+			Severity: severityWarning,
+		})
+	}
+
+	return errs
+}
+
+// densestKeyword returns the word that makes up the largest fraction of the
+// words in `text`, ignoring case, and the fraction. Short words, e.g. articles
+// and prepositions, and short texts are left out since they are naturally
+// repetitive.
+func densestKeyword(text string) (string, float64) {
+	const minWords, minLength, minCount = 50, 4, 5
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	if len(words) < minWords {
+		return "", 0
+	}
+
+	counts := make(map[string]int)
+	densest := ""
+	for _, w := range words {
+		if utf8.RuneCountInString(w) < minLength {
+			continue
+		}
+
+		counts[w]++
+		if counts[w] > counts[densest] || counts[w] == counts[densest] && w < densest {
+			densest = w
+		}
+	}
+
+	if counts[densest] < minCount {
+		return "", 0
+	}
+
+	return densest, float64(counts[densest]) / float64(len(words))
+}
+
+// keywordBlockSize returns the number of items in `line` if it is a list of
+// comma-separated keywords, e.g. "music, player, mp3, audio, ...", or 0.
+func keywordBlockSize(line string) int {
+	const minItems, maxItemWords = 8, 3
+	items := strings.FieldsFunc(line, func(r rune) bool { return r == ',' || r == '、' || r == '，' })
+	if len(items) < minItems {
+		return 0
+	}
+
+	for _, item := range items {
+		if n := len(strings.Fields(item)); n == 0 || n > maxItemWords {
+			return 0
+		}
+	}
+
+	return len(items)
+}
+
+// checkSingleLine checks that the title and the short description of the
+// locale are a single line without leading or trailing whitespace, apart from
+// the final newline. supply uploads them verbatim, so line breaks show up as
+// whitespace in the listing.
+func checkSingleLine(localePath string) []error {
+	errs := make([]error, 0)
+	for _, file := range []string{"title.txt", "short_description.txt"} {
+		filePath := layout.textPath(localePath, file)
+		content, err := readFile(filePath)
+		if err != nil {
+			continue // already reported by checkDescriptiveTexts
+		}
+
+		text := strings.TrimSuffix(strings.TrimSuffix(string(content), "\n"), "\r")
+		if strings.TrimSpace(text) == "" {
+			continue // reported by checkDescriptiveTexts
+		}
+
+		issues := make([]string, 0)
+		if strings.TrimLeftFunc(text, unicode.IsSpace) != text {
+			issues = append(issues, "leading whitespace")
+		}
+
+		if strings.TrimRightFunc(text, unicode.IsSpace) != text {
+			issues = append(issues, "trailing whitespace")
+		}
+
+		if strings.ContainsAny(strings.TrimSpace(text), "\r\n") {
+			issues = append(issues, "embedded line breaks")
+		}
+
+		if len(issues) > 0 {
+			const errFmt = "content must be a single trimmed line: found %s"
+			errs = append(errs, &validationError{
+				File: filePath,
+				Rule: "single-line",
+				Err:  fmt.Errorf(errFmt, strings.Join(issues, ", ")),
+			})
+		}
+	}
+
+	return errs
+}
+
+// checkMarkdown warns if the descriptive texts of the locale contain Markdown
+// syntax, e.g. copied from a README. Stores render it as raw characters.
+func checkMarkdown(localePath string) []error {
+	errs := make([]error, 0)
+	for _, file := range []string{"title.txt", "short_description.txt", "full_description.txt"} {
+		filePath := layout.textPath(localePath, file)
+		content, err := readFile(filePath)
+		if err != nil {
+			continue // already reported by checkDescriptiveTexts
+		}
+
+		text := string(content)
+		if loc := markdownRegexp.FindStringIndex(text); loc != nil {
+			const errFmt = "Markdown syntax renders as raw characters: found %q on line %d"
+			line, _ := lineColumn(text, loc[0])
+			errs = append(errs, &validationError{
+				File:     filePath,
+				Rule:     "markdown",
+				Err:      fmt.Errorf(errFmt, text[loc[0]:loc[1]], line),
+				Severity: severityWarning,
+			})
+		}
+	}
+
+	return errs
+}
+
+// checkPlaceholders checks that the text file at `filePath` doesn't contain
+// placeholder text, e.g. "Lorem ipsum" or "TODO", matching the default or the
+// configured placeholder patterns.
+func checkPlaceholders(filePath string) []error {
+	content, err := readFile(filePath)
+	if err != nil {
+		return nil // already reported by the caller
+	}
+
+	for _, re := range cfg.placeholderRegexps {
+		if match := re.FindString(string(content)); match != "" {
+			const errFmt = "content seems to be a placeholder: found %q"
+			return []error{&validationError{
+				File: filePath,
+				Rule: "placeholder",
+				Err:  fmt.Errorf(errFmt, match),
+			}}
+		}
+	}
+
+	return nil
+}
+
+// checkBannedWords checks that the text file at `filePath` doesn't contain any
+// of the terms that the config bans in its locale.
+func checkBannedWords(filePath string) []error {
+	content, err := readFile(filePath)
+	if err != nil {
+		return nil // already reported by the caller
+	}
+
+	locale := filepath.Base(layout.localePath(filePath))
+	if found := findWords(string(content), wordListRegexps(cfg.BannedWords, locale)); len(found) > 0 {
+		const errFmt = "content contains banned words: found %s"
+		return []error{&validationError{
+			File: filePath,
+			Rule: "banned-word",
+			Err:  fmt.Errorf(errFmt, strings.Join(found, ", ")),
+		}}
+	}
+
+	return nil
+}
+
+// checkProfanity checks that the text file at `filePath` doesn't contain any of
+// the offensive terms in the profanity lists that apply to its locale.
+func checkProfanity(filePath string) []error {
+	if cfg.Profanity == nil {
+		return nil
+	}
+
+	content, err := readFile(filePath)
+	if err != nil {
+		return nil // already reported by the caller
+	}
+
+	locale := filepath.Base(layout.localePath(filePath))
+	if found := findWords(string(content), wordListRegexps(cfg.Profanity.Lists, locale)); len(found) > 0 {
+		const errFmt = "content contains offensive language: found %s"
+		return []error{&validationError{
+			File:     filePath,
+			Rule:     "profanity",
+			Err:      fmt.Errorf(errFmt, strings.Join(found, ", ")),
+			Severity: cfg.Profanity.severity,
+		}}
+	}
+
+	return nil
+}
+
+// findWords returns the quoted terms that `regexps`, compiled by
+// `wordListRule.compile`, find in `text`.
+func findWords(text string, regexps []*regexp.Regexp) []string {
+	found := make([]string, 0)
+	for _, re := range regexps {
+		if m := re.FindStringSubmatch(text); m != nil {
+			if q := strconv.Quote(m[1]); !containsString(found, q) {
+				found = append(found, q)
+			}
+		}
+	}
+
+	return found
+}
+
+// checkHTMLTags checks that the text file at `filePath` only contains the
+// HTML tags that the active profile allows, and that they are closed. Stores
+// show other tags as literal text.
+func checkHTMLTags(filePath string) []error {
+	content, err := readFile(filePath)
+	if err != nil {
+		return nil // already reported by checkDescriptiveTexts
+	}
+
+	text := string(content)
+	errs := make([]error, 0)
+	report := func(errFmt string, tag string, offset int) {
+		line, column := lineColumn(text, offset)
+		errs = append(errs, &validationError{
+			File: filePath,
+			Rule: "html-tags",
+			Err:  fmt.Errorf(errFmt, tag, line, column),
+		})
+	}
+
+	type openTag struct {
+		name   string
+		offset int
+	}
+
+	open := make([]openTag, 0)
+	for _, m := range htmlTagRegexp.FindAllStringSubmatchIndex(text, -1) {
+		tag, name := text[m[0]:m[1]], strings.ToLower(text[m[2]:m[3]])
+		if !containsString(activeProfile.htmlTags, name) {
+			report("unsupported HTML tag %q on line %d, column %d", tag, m[0])
+			continue
+		}
+
+		if containsString(voidHTMLTags, name) || strings.HasSuffix(tag, "/>") {
+			continue
+		}
+
+		if !strings.HasPrefix(tag, "</") {
+			open = append(open, openTag{name: name, offset: m[0]})
+			continue
+		}
+
+		i := len(open) - 1
+		for i >= 0 && open[i].name != name {
+			i--
+		}
+
+		if i < 0 {
+			report("closing HTML tag %q without an opening tag on line %d, column %d", tag, m[0])
+			continue
+		}
+
+		for _, t := range open[i+1:] {
+			if !containsString(optionalEndHTMLTags, t.name) {
+				report("unclosed HTML tag %q on line %d, column %d", "<"+t.name+">", t.offset)
+			}
+		}
+
+		open = open[:i]
+	}
+
+	for _, t := range open {
+		if !containsString(optionalEndHTMLTags, t.name) {
+			report("unclosed HTML tag %q on line %d, column %d", "<"+t.name+">", t.offset)
+		}
+	}
+
+	return errs
+}
+
+var (
+	// voidHTMLTags never have a closing tag.
+	voidHTMLTags = []string{"br", "hr", "img"}
+	// optionalEndHTMLTags may be closed implicitly by their parent's closing tag
+	// or the end of the text.
+	optionalEndHTMLTags = []string{"li", "p"}
+)
+
+// lineColumn returns the 1-based line and column (in characters) of the byte
+// `offset` in `text`.
+func lineColumn(text string, offset int) (int, int) {
+	before := text[:offset]
+	line := strings.Count(before, "\n") + 1
+	column := utf8.RuneCountInString(before[strings.LastIndex(before, "\n")+1:]) + 1
+	return line, column
+}
+
+var youTubeIDRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+
+// checkVideoURL checks that the promo video file at `filePath`, if it exists,
+// contains exactly one YouTube video URL. Links to shorts, playlists and
+// channels aren't accepted by Play.
+func checkVideoURL(filePath string) []error {
+	content, err := readFile(filePath)
+	if err != nil {
+		return nil // the promo video is optional
+	}
+
+	text := strings.TrimSpace(string(content))
+	if text == "" {
+		return nil
+	}
+
+	if fields := strings.Fields(text); len(fields) > 1 {
+		const errFmt = "must contain exactly one YouTube URL: got=%d words"
+		return []error{&validationError{
+			File: filePath,
+			Rule: "video-url",
+			Err:  fmt.Errorf(errFmt, len(fields)),
+		}}
+	}
+
+	if err := validateYouTubeURL(text); err != nil {
+		return []error{&validationError{
+			File: filePath,
+			Rule: "video-url",
+			Err:  err,
+		}}
+	}
+
+	return nil
+}
+
+// validateYouTubeURL returns an error if `rawURL` isn't a YouTube watch URL,
+// e.g. `https://www.youtube.com/watch?v=<id>` or `https://youtu.be/<id>`.
+func validateYouTubeURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("must be a YouTube video URL: got=%q", rawURL)
+	}
+
+	if videoRequireHTTPS && u.Scheme != "https" {
+		return fmt.Errorf("YouTube URL must use HTTPS: got=%q", rawURL)
+	}
+
+	id := ""
+	switch strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(u.Host), "www."), "m.") {
+	case "youtube.com":
+		if u.Path != "/watch" {
+			const errFmt = "must be a YouTube watch URL, not a shorts, playlist or channel link: got=%q"
+			return fmt.Errorf(errFmt, rawURL)
+		}
+
+		id = u.Query().Get("v")
+	case "youtu.be":
+		id = strings.TrimPrefix(u.Path, "/")
+	default:
+		return fmt.Errorf("must be a YouTube video URL: got=%q", rawURL)
+	}
+
+	if !youTubeIDRegexp.MatchString(id) {
+		return fmt.Errorf("YouTube URL has an invalid video ID: got=%q", id)
+	}
+
+	return nil
+}
+
+// checkTitleSymbols checks that the title at `filePath` doesn't contain
+// trademark symbols (™, ®, ©) or stylised letters unless they are present in
+// `titleAllowedSymbols`. Play's policy review frequently rejects such titles.
+func checkTitleSymbols(filePath string) []error {
+	content, err := readFile(filePath)
+	if err != nil {
+		return nil // already reported by checkDescriptiveTexts
+	}
+
+	found := make([]string, 0)
+	for _, r := range string(content) {
+		if isDecorativeSymbol(r) && !strings.ContainsRune(titleAllowedSymbols, r) {
+			if q := strconv.QuoteRune(r); !containsString(found, q) {
+				found = append(found, q)
+			}
+		}
+	}
+
+	if len(found) > 0 {
+		const errFmt = "title must not contain trademark symbols or stylised letters: found %s"
+		return []error{&validationError{
+			File: filePath,
+			Rule: "title-symbols",
+			Err:  fmt.Errorf(errFmt, strings.Join(found, ", ")),
+		}}
+	}
+
+	return nil
+}
+
+// checkTitleClaims checks that the title at `filePath` doesn't contain the
+// promotional or performance claims that the active profile forbids in app
+// names, e.g. "free", "best", "#1" or prices.
+func checkTitleClaims(filePath string) []error {
+	content, err := readFile(filePath)
+	if err != nil {
+		return nil // already reported by checkDescriptiveTexts
+	}
+
+	found := make([]string, 0)
+	for _, re := range activeProfile.titleClaims {
+		for _, m := range re.FindAllString(string(content), -1) {
+			if q := strconv.Quote(m); !containsString(found, q) {
+				found = append(found, q)
+			}
+		}
+	}
+
+	if len(found) > 0 {
+		const errFmt = "title must not contain promotional or performance claims: found %s"
+		return []error{&validationError{
+			File:     filePath,
+			Rule:     "title-claims",
+			Err:      fmt.Errorf(errFmt, strings.Join(found, ", ")),
+			Severity: activeProfile.titleClaimSeverity,
+		}}
+	}
+
+	return nil
+}
+
+// isDecorativeSymbol reports whether `r` is a trademark symbol or a character
+// commonly used to stylise text, e.g. mathematical or enclosed letters.
+func isDecorativeSymbol(r rune) bool {
+	switch {
+	case r == '™', r == '®', r == '©', r == '℠':
+		return true
+	case r >= 0x1D400 && r <= 0x1D7FF: // mathematical alphanumeric symbols
+		return true
+	case r >= 0x2460 && r <= 0x24FF: // enclosed alphanumerics
+		return true
+	}
+
+	return false
+}
+
+// isPictographicSymbol reports whether `r` is one of the symbols outside the
+// emoji blocks that render as emoji, e.g. ⌚ or ▶. Other symbols, e.g. ° or
+// №, are ordinary typography.
+func isPictographicSymbol(r rune) bool {
+	switch {
+	case r == 0x2139, r >= 0x2194 && r <= 0x2199, r == 0x21A9, r == 0x21AA: // letterlike symbols and arrows
+		return true
+	case r == 0x231A, r == 0x231B, r == 0x2328, r == 0x23CF, r >= 0x23E9 && r <= 0x23F3, r >= 0x23F8 && r <= 0x23FA: // misc technical
+		return true
+	case r == 0x25AA, r == 0x25AB, r == 0x25B6, r == 0x25C0, r >= 0x25FB && r <= 0x25FE: // geometric shapes
+		return true
+	case r == 0x3030, r == 0x303D, r == 0x3297, r == 0x3299: // CJK symbols
+		return true
+	}
+
+	return false
+}
+
+// checkEmojiPolicy checks the descriptive texts of the locale for emoji,
+// dingbats and other pictographic symbols with the severities that the active
+// profile declares.
+func checkEmojiPolicy(localePath string) []error {
+	files := make([]string, 0, len(activeProfile.emojiPolicy))
+	for file := range activeProfile.emojiPolicy {
+		files = append(files, file)
+	}
+
+	sort.Strings(files)
+	errs := make([]error, 0)
+	for _, file := range files {
+		filePath := layout.textPath(localePath, file)
+		content, err := readFile(filePath)
+		if err != nil {
+			continue // already reported by checkDescriptiveTexts
+		}
+
+		found := make([]string, 0)
+		for _, r := range string(content) {
+			if file == "title.txt" && strings.ContainsRune(titleAllowedSymbols, r) {
+				continue
+			}
+
+			if r == 0x200D || r == 0xFE0F {
+				continue // only compose the emoji around them
+			}
+
+			if isEmoji(r) || isPictographicSymbol(r) {
+				if q := strconv.QuoteRune(r); !containsString(found, q) {
+					found = append(found, q)
+				}
+			}
+		}
+
+		if len(found) > 0 {
+			const errFmt = "emoji and decorative symbols are restricted by store policy: found %s"
+			errs = append(errs, &validationError{
+				File:     filePath,
+				Rule:     "emoji",
+				Err:      fmt.Errorf(errFmt, strings.Join(found, ", ")),
+				Severity: activeProfile.emojiPolicy[file],
+			})
+		}
+	}
+
+	return errs
+}
+
+func containsString(s []string, v string) bool {
+	for _, i := range s {
+		if i == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkDescriptionOverlap warns if a large part of the short description is
+// copied verbatim from the full description. The short description should be a
+// distinct hook rather than a duplicate.
+func checkDescriptionOverlap(localePath string) []error {
+	if descriptionOverlap <= 0 {
+		return nil
+	}
+
+	shortDescPath := layout.textPath(localePath, "short_description.txt")
+	shortDesc, err := readFile(shortDescPath)
+	if err != nil {
+		return nil // already reported by checkDescriptiveTexts
+	}
+
+	fullDesc, err := readFile(layout.textPath(localePath, "full_description.txt"))
+	if err != nil {
+		return nil // already reported by checkDescriptiveTexts
+	}
+
+	short := []rune(strings.ToLower(strings.TrimSpace(string(shortDesc))))
+	full := []rune(strings.ToLower(strings.TrimSpace(string(fullDesc))))
+	if len(short) == 0 {
+		return nil
+	}
+
+	overlap := float64(longestCommonSubstring(short, full)) / float64(len(short))
+	if overlap >= descriptionOverlap {
+		const errFmt = "%.0f%% of the short description is copied from the full description: use it for a distinct hook instead"
+		return []error{&validationError{
+			File:     shortDescPath,
+			Rule:     "description-overlap",
+			Err:      fmt.Errorf(errFmt, overlap*100),
+			Severity: severityWarning,
+		}}
+	}
+
+	return nil
+}
+
+// longestCommonSubstring returns the length of the longest common substring of
+// `a` and `b`.
+func longestCommonSubstring(a, b []rune) int {
+	longest := 0
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			if a[i-1] == b[j-1] {
+				curr[j] = prev[j-1] + 1
+				if curr[j] > longest {
+					longest = curr[j]
+				}
+			} else {
+				curr[j] = 0
+			}
+		}
+
+		prev, curr = curr, prev
+	}
+
+	return longest
+}
+
+// checkRepeatedWords warns about accidental immediate word repetitions, e.g.
+// "the the", in the short and the full descriptions.
+func checkRepeatedWords(localePath string) []error {
+	errs := make([]error, 0)
+	for _, file := range []string{"short_description.txt", "full_description.txt"} {
+		errs = append(errs, checkRepeatedWordsInFile(layout.textPath(localePath, file))...)
+	}
+
+	return errs
+}
+
+// checkRepeatedWordsInFile warns about accidental immediate word repetitions
+// in the text file at `filePath`.
+func checkRepeatedWordsInFile(filePath string) []error {
+	content, err := readFile(filePath)
+	if err != nil {
+		return nil // already reported by the caller
+	}
+
+	errs := make([]error, 0)
+	for _, r := range findRepeatedWords(string(content)) {
+		const errFmt = "repeated word %q on line %d"
+		errs = append(errs, &validationError{
+			File:     filePath,
+			Rule:     "repeated-word",
+			Err:      fmt.Errorf(errFmt, r.word, r.line),
+			Severity: severityWarning,
+		})
+	}
+
+	return errs
+}
+
+type repeatedWord struct {
+	word string
+	line int
+}
+
+// findRepeatedWords returns words that are immediately followed by the same
+// word, ignoring case. Words separated by anything other than whitespace are
+// not considered repeated.
+func findRepeatedWords(text string) []repeatedWord {
+	found := make([]repeatedWord, 0)
+	line, prev, word := 1, "", strings.Builder{}
+	separatedBySpace := false
+	flush := func() {
+		w := strings.ToLower(word.String())
+		word.Reset()
+		if w == "" {
+			return
+		}
+
+		if separatedBySpace && w == prev {
+			found = append(found, repeatedWord{word: w, line: line})
+		}
+
+		prev, separatedBySpace = w, true
+	}
+
+	for _, r := range text {
+		switch {
+		case unicode.IsLetter(r), unicode.IsMark(r), unicode.IsDigit(r):
+			word.WriteRune(r)
+		case unicode.IsSpace(r):
+			flush()
+			if r == '\n' {
+				line++
+			}
+		default:
+			flush()
+			separatedBySpace = false
+		}
+	}
+
+	flush()
+	return found
+}
+
+// binarySignatures maps the magic numbers of binary formats commonly mistaken
+// for text files to their descriptions.
+var binarySignatures = []struct {
+	magic string
+	kind  string
+}{
+	{"%PDF-", "a PDF document"},
+	{"PK\x03\x04", "a ZIP archive (e.g. DOCX, ODT)"},
+	{"\xD0\xCF\x11\xE0", "an OLE document (e.g. DOC)"},
+	{"{\\rtf", "an RTF document"},
+	{"\x89PNG", "a PNG image"},
+	{"\xFF\xD8\xFF", "a JPEG image"},
+	{"GIF8", "a GIF image"},
+}
+
+// checkEncoding checks that the text file at `filePath` is UTF-8 encoded
+// without a byte order mark, which supply uploads verbatim, and that it doesn't
+// contain control characters other than line breaks and tabs, which are
+// reported by checkWhitespace.
+func checkEncoding(filePath string) []error {
+	content, err := readFile(filePath)
+	if err != nil || sniffBinary(content) != "" {
+		return nil // already reported by the caller
+	}
+
+	report := func(err error) []error {
+		return []error{&validationError{File: filePath, Rule: "encoding", Err: err}}
+	}
+
+	switch {
+	case bytes.HasPrefix(content, []byte{0xFE, 0xFF}), bytes.HasPrefix(content, []byte{0xFF, 0xFE}):
+		return report(fmt.Errorf("content must be UTF-8: found UTF-16"))
+	case bytes.HasPrefix(content, []byte{0xEF, 0xBB, 0xBF}):
+		return report(fmt.Errorf("content must not start with a byte order mark: supply uploads it verbatim"))
+	}
+
+	text := string(content)
+	found := make([]string, 0)
+	firstLine := 0
+	for i, r := range text {
+		if r == utf8.RuneError {
+			if _, size := utf8.DecodeRuneInString(text[i:]); size == 1 {
+				const errFmt = "content must be UTF-8: found invalid byte 0x%02X on line %d"
+				line, _ := lineColumn(text, i)
+				return report(fmt.Errorf(errFmt, text[i], line))
+			}
+		}
+
+		if r == '\n' || r == '\r' && strings.HasPrefix(text[i+1:], "\n") || r == '\t' || !unicode.IsControl(r) {
+			continue
+		}
+
+		if q := fmt.Sprintf("%U", r); !containsString(found, q) {
+			found = append(found, q)
+		}
+
+		if firstLine == 0 {
+			firstLine, _ = lineColumn(text, i)
+		}
+	}
+
+	if len(found) > 0 {
+		const errFmt = "content must not contain control characters: found %s, first on line %d"
+		return report(fmt.Errorf(errFmt, strings.Join(found, ", "), firstLine))
+	}
+
+	return nil
+}
+
+// checkConflictMarkers checks that the text file at `filePath` doesn't contain
+// the markers of an unresolved merge conflict, which supply would upload as
+// they are.
+func checkConflictMarkers(filePath string) []error {
+	content, err := readFile(filePath)
+	if err != nil || sniffBinary(content) != "" {
+		return nil // already reported by the caller
+	}
+
+	text := string(content)
+	lines := make([]string, 0)
+	for _, loc := range conflictRegexp.FindAllStringIndex(text, -1) {
+		line, _ := lineColumn(text, loc[0])
+		lines = append(lines, strconv.Itoa(line))
+	}
+
+	if len(lines) > 0 {
+		const errFmt = "found merge conflict markers on lines %s"
+		return []error{&validationError{
+			File: filePath,
+			Rule: "conflict-markers",
+			Err:  fmt.Errorf(errFmt, strings.Join(lines, ", ")),
+		}}
+	}
+
+	return nil
+}
+
+// windows1252Bytes maps the characters that Windows-1252 assigns to the bytes
+// 0x80-0x9F, where Latin-1 has control characters, back to those bytes.
+var windows1252Bytes = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C, 'Ž': 0x8E, '‘': 0x91,
+	'’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '˜': 0x98,
+	'™': 0x99, 'š': 0x9A, '›': 0x9B, 'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+// latin1Byte returns the byte that `r` encodes in Latin-1 or Windows-1252, if
+// any.
+func latin1Byte(r rune) (byte, bool) {
+	if r >= 0x80 && r <= 0xFF {
+		return byte(r), true
+	}
+
+	b, ok := windows1252Bytes[r]
+	return b, ok
+}
+
+// findMojibake returns the first sequence of characters in `text` that is the
+// UTF-8 encoding of a non-ASCII character decoded as Latin-1 or Windows-1252,
+// its byte offset and the character it was meant to be. It returns -1 if there
+// is none. Only the common signatures count, i.e. "Ã", "Â" or "Å" followed by
+// a continuation byte, e.g. "Ã©" for "é", and "â€" followed by one, e.g. "â€™"
+// for "’", since other sequences occur in correct text, e.g. "é »" in French.
+func findMojibake(text string) (string, int, rune) {
+	runes := []rune(text)
+	offset := 0
+	for i, r := range runes {
+		var encoded []byte
+		switch {
+		case r == 'Ã' || r == 'Â' || r == 'Å':
+			encoded = []byte{byte(r)}
+		case r == 'â' && i+1 < len(runes) && runes[i+1] == '€':
+			encoded = []byte{0xE2, 0x80}
+		}
+
+		if n := len(encoded); n > 0 && i+n < len(runes) {
+			if b, ok := latin1Byte(runes[i+n]); ok && b >= 0x80 && b <= 0xBF {
+				decoded, _ := utf8.DecodeRune(append(encoded, b))
+				return string(runes[i : i+n+1]), offset, decoded
+			}
+		}
+
+		offset += utf8.RuneLen(r)
+	}
+
+	return "", -1, 0
+}
+
+// checkMojibake checks that the changelog at `filePath`, e.g. one from a
+// translation vendor, wasn't garbled by a broken export: UTF-8 text decoded
+// as Latin-1 or Windows-1252, e.g. "Ã©" or "â€™", or characters replaced by
+// U+FFFD because they couldn't be converted. Play shows both as they are.
+func checkMojibake(filePath string) []error {
+	content, err := readFile(filePath)
+	if err != nil || sniffBinary(content) != "" || !utf8.Valid(content) {
+		return nil // already reported by checkEncoding
+	}
+
+	text := string(content)
+	errs := make([]error, 0)
+	if seq, i, r := findMojibake(text); i >= 0 {
+		const errFmt = "found %q, probably %q garbled by a wrong encoding, on line %d"
+		line, _ := lineColumn(text, i)
+		errs = append(errs, &validationError{
+			File: filePath,
+			Rule: "mojibake",
+			Err:  fmt.Errorf(errFmt, seq, string(r), line),
+		})
+	}
+
+	if i := strings.IndexRune(text, utf8.RuneError); i >= 0 {
+		const errFmt = "found the replacement character U+FFFD on line %d: characters were lost in a conversion"
+		line, _ := lineColumn(text, i)
+		errs = append(errs, &validationError{
+			File: filePath,
+			Rule: "mojibake",
+			Err:  fmt.Errorf(errFmt, line),
+		})
+	}
+
+	return errs
+}
+
+// expectedLineEnding is the line ending that text files must use: "\n",
+// "\r\n", or empty if `-line-endings` is `any`. With `consistent`, it is the
+// line ending used by most text files.
+var expectedLineEnding = "\n"
+
+// checkLineEndings checks that the text file at `filePath` consistently uses
+// the expected line ending. Play shows the descriptions that supply uploads
+// with CRLF line endings double-spaced. With `-fix`, it converts the file
+// instead.
+func checkLineEndings(filePath string) []error {
+	content, err := readFile(filePath)
+	if err != nil || expectedLineEnding == "" || sniffBinary(content) != "" {
+		return nil // already reported by the caller
+	}
+
+	crlf := bytes.Count(content, []byte("\r\n"))
+	lf := bytes.Count(content, []byte("\n")) - crlf
+	var errMsg error
+	switch {
+	case crlf > 0 && lf > 0:
+		const errFmt = "mixed line endings: found %d CRLF and %d LF"
+		errMsg = fmt.Errorf(errFmt, crlf, lf)
+	case crlf > 0 && expectedLineEnding == "\n":
+		errMsg = fmt.Errorf("expected LF line endings: found CRLF")
+	case lf > 0 && expectedLineEnding == "\r\n":
+		errMsg = fmt.Errorf("expected CRLF line endings: found LF")
+	default:
+		return nil
+	}
+
+	if fixWhitespace {
+		text := strings.ReplaceAll(string(content), "\r\n", "\n")
+		if err := writeFile(filePath, []byte(strings.ReplaceAll(text, "\n", expectedLineEnding))); err != nil {
+			const errFmt = "failed to fix file %q: %w"
+			return []error{fmt.Errorf(errFmt, filePath, err)}
+		}
+
+		return nil
+	}
+
+	return []error{&validationError{
+		File: filePath,
+		Rule: "line-endings",
+		Err:  errMsg,
+	}}
+}
+
+// dominantLineEnding returns the line ending used by most text files in the
+// locale trees, or LF if there's a tie.
+func dominantLineEnding(trees []localeTree) string {
+	crlf, lf := 0, 0
+	for _, t := range trees {
+		_ = walkFiles(t.path, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() || filepath.Ext(path) != ".txt" || isSkipped(path) {
+				return nil
+			}
+
+			content, err := readFile(path)
+			if err != nil {
+				return nil
+			}
+
+			if bytes.Contains(content, []byte("\r\n")) {
+				crlf++
+			} else if bytes.Contains(content, []byte("\n")) {
+				lf++
+			}
+
+			return nil
+		})
+	}
+
+	if crlf > lf {
+		return "\r\n"
+	}
+
+	return "\n"
+}
+
+// checkWhitespace warns about trailing spaces, consecutive blank lines, tabs
+// and a missing final newline in the text file at `filePath`. They churn diffs
+// and may count against the length limits. With `-fix`, it normalises the file
+// instead.
+func checkWhitespace(filePath string) []error {
+	content, err := readFile(filePath)
+	if err != nil || len(content) == 0 || sniffBinary(content) != "" {
+		return nil // already reported by the caller
+	}
+
+	text := string(content)
+	newline := "\n"
+	if strings.Contains(text, "\r\n") {
+		newline = "\r\n"
+	}
+
+	lines := strings.Split(strings.TrimSuffix(text, newline), newline)
+	fixed := make([]string, 0, len(lines))
+	issues := make([]string, 0)
+	addIssue := func(issue string, line int) {
+		for _, i := range issues {
+			if strings.HasPrefix(i, issue+" ") {
+				return
+			}
+		}
+
+		issues = append(issues, fmt.Sprintf("%s on line %d", issue, line))
+	}
+
+	for i, line := range lines {
+		if strings.Contains(line, "\t") {
+			addIssue("tabs", i+1)
+			line = strings.ReplaceAll(line, "\t", " ")
+		}
+
+		if trimmed := strings.TrimRight(line, " \u00A0"); trimmed != line {
+			addIssue("trailing spaces", i+1)
+			line = trimmed
+		}
+
+		if line == "" && i > 0 && fixed[len(fixed)-1] == "" {
+			addIssue("consecutive blank lines", i+1)
+			continue
+		}
+
+		fixed = append(fixed, line)
+	}
+
+	for len(fixed) > 0 && fixed[len(fixed)-1] == "" {
+		fixed = fixed[:len(fixed)-1]
+		addIssue("blank lines at the end", len(fixed)+1)
+	}
+
+	if !strings.HasSuffix(text, newline) {
+		issues = append(issues, "no final newline")
+	}
+
+	if len(issues) == 0 {
+		return nil
+	}
+
+	if fixWhitespace {
+		if err := writeFile(filePath, []byte(strings.Join(fixed, newline)+newline)); err != nil {
+			const errFmt = "failed to fix file %q: %w"
+			return []error{fmt.Errorf(errFmt, filePath, err)}
+		}
+
+		return nil
+	}
+
+	const errFmt = "found %s; run with -fix to normalise whitespace"
+	return []error{&validationError{
+		File:     filePath,
+		Rule:     "whitespace",
+		Err:      fmt.Errorf(errFmt, strings.Join(issues, ", ")),
+		Severity: severityWarning,
+	}}
+}
+
+// invisibleCharacters are the characters that have no width and are usually
+// pasted by accident. They render bizarrely and make the lengths that Play
+// Console shows disagree with the visible text.
+var invisibleCharacters = map[rune]string{
+	0x00AD: "soft hyphen",
+	0x200B: "zero-width space",
+	0x200D: "zero-width joiner",
+	0x2060: "word joiner",
+	0xFEFF: "zero-width no-break space",
+	0x202A: "left-to-right embedding",
+	0x202B: "right-to-left embedding",
+	0x202C: "pop directional formatting",
+	0x202D: "left-to-right override",
+	0x202E: "right-to-left override",
+	0x2066: "left-to-right isolate",
+	0x2067: "right-to-left isolate",
+	0x2068: "first strong isolate",
+	0x2069: "pop directional isolate",
+}
+
+// checkInvisibleCharacters checks that the title and the descriptions of the
+// locale don't contain invisible characters. Zero-width joiners are allowed
+// between emoji and between letters of the scripts that use them to shape
+// text, e.g. Devanagari.
+func checkInvisibleCharacters(localePath string) []error {
+	errs := make([]error, 0)
+	for _, file := range []string{"title.txt", "short_description.txt", "full_description.txt"} {
+		filePath := layout.textPath(localePath, file)
+		content, err := readFile(filePath)
+		if err != nil {
+			continue // already reported by checkDescriptiveTexts
+		}
+
+		text := []rune(strings.TrimPrefix(string(content), "\uFEFF")) // reported by checkEncoding
+		found := make([]string, 0)
+		firstLine := 0
+		for i, r := range text {
+			name, ok := invisibleCharacters[r]
+			if !ok || r == 0x200D && i > 0 && i+1 < len(text) && joinsCharacters(text[i-1], text[i+1]) {
+				continue
+			}
+
+			if q := fmt.Sprintf("%U (%s)", r, name); !containsString(found, q) {
+				found = append(found, q)
+			}
+
+			if firstLine == 0 {
+				firstLine = strings.Count(string(text[:i]), "\n") + 1
+			}
+		}
+
+		if len(found) > 0 {
+			const errFmt = "content must not contain invisible characters: found %s, first on line %d"
+			errs = append(errs, &validationError{
+				File: filePath,
+				Rule: "invisible-characters",
+				Err:  fmt.Errorf(errFmt, strings.Join(found, ", "), firstLine),
+			})
+		}
+	}
+
+	return errs
+}
+
+// joinsCharacters reports whether a zero-width joiner between `prev` and `next`
+// is meaningful, i.e. composes an emoji sequence or shapes a non-Latin script.
+func joinsCharacters(prev, next rune) bool {
+	if isEmoji(prev) && isEmoji(next) {
+		return true
+	}
+
+	if unicode.In(prev, unicode.Latin, unicode.Common) || unicode.In(next, unicode.Latin, unicode.Common) {
+		return false
+	}
+
+	return unicode.In(prev, unicode.L, unicode.M) && unicode.In(next, unicode.L, unicode.M)
+}
+
+// sniffBinary returns the description of the binary format of `content`, or an
+// empty string if it seems to be text.
+func sniffBinary(content []byte) string {
+	for _, s := range binarySignatures {
+		if bytes.HasPrefix(content, []byte(s.magic)) {
+			return s.kind
+		}
+	}
+
+	// UTF-16 text contains NUL bytes, but starts with a byte order mark.
+	if bytes.HasPrefix(content, []byte{0xFE, 0xFF}) || bytes.HasPrefix(content, []byte{0xFF, 0xFE}) {
+		return ""
+	}
+
+	if len(content) > 512 {
+		content = content[:512]
+	}
+
+	for _, b := range content {
+		if b < 0x20 && b != '\t' && b != '\n' && b != '\r' && b != '\f' && b != 0x1B {
+			return "binary data"
+		}
+	}
+
+	return ""
+}
+
+// isEmojiOnly reports whether `text` contains at least one emoji and nothing
+// else apart from whitespace and punctuation.
+func isEmojiOnly(text string) bool {
+	found := false
+	for _, r := range text {
+		switch {
+		case isEmoji(r):
+			found = true
+		case unicode.IsSpace(r), unicode.IsPunct(r):
+		default:
+			return false
+		}
+	}
+
+	return found
+}
+
+// isEmoji reports whether `r` is an emoji, an emoji modifier or a character
+// used to compose emoji sequences.
+func isEmoji(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF: // emoticons, pictographs, flags, etc.
+		return true
+	case r >= 0x2600 && r <= 0x27BF: // misc symbols and dingbats
+		return true
+	case r >= 0x2B00 && r <= 0x2BFF: // misc symbols and arrows
+		return true
+	case r == 0x200D, r == 0xFE0F, r == 0x20E3: // ZWJ, variation selector, keycap
+		return true
+	case r >= 0xE0020 && r <= 0xE007F: // tag sequences
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// versionCodeFiles are the files that `-require-changelog-for auto` reads the
+// version code from, in order, unless `-version-code-file` is set. The build
+// outputs come first, since they contain the final version code even if the
+// build script computes it.
+var versionCodeFiles = []string{
+	"./app/build/outputs/apk/release/output-metadata.json",
+	"./app/build/intermediates/apk/release/output-metadata.json",
+	"./app/build.gradle.kts",
+	"./app/build.gradle",
+}
+
+var gradleVersionCodeRegexp = regexp.MustCompile(`(?m)^\s*versionCode\s*(?:=\s*)?(\d+)\s*$`)
+
+// resolveRequiredChangelog returns the version code that
+// `-require-changelog-for` and `-version-code-file` require a changelog for,
+// or 0 if neither is set.
+func resolveRequiredChangelog(spec, file string) (int, error) {
+	if spec == "" && file == "" {
+		return 0, nil
+	}
+
+	if spec != "" && spec != "auto" {
+		code, err := strconv.Atoi(spec)
+		if err != nil || code <= 0 {
+			return 0, fmt.Errorf("expected a positive version code or auto, got %q", spec)
+		}
+
+		return code, nil
+	}
+
+	if file != "" {
+		return readVersionCode(file)
+	}
+
+	for _, f := range versionCodeFiles {
+		if _, err := os.Stat(f); err == nil {
+			return readVersionCode(f)
+		}
+	}
+
+	return 0, fmt.Errorf("found none of %s", strings.Join(versionCodeFiles, ", "))
+}
+
+// readVersionCode reads the version code from the Gradle build script, the
+// `output-metadata.json` of the Android Gradle plugin, or the plain text file
+// at `path`.
+func readVersionCode(path string) (int, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case strings.HasSuffix(path, ".gradle"), strings.HasSuffix(path, ".gradle.kts"):
+		matches := gradleVersionCodeRegexp.FindAllSubmatch(content, -1)
+		if len(matches) != 1 {
+			const errFmt = "%s: expected a single literal versionCode, found %d"
+			return 0, fmt.Errorf(errFmt, path, len(matches))
+		}
+
+		return strconv.Atoi(string(matches[0][1]))
+	case strings.HasSuffix(path, ".json"):
+		var metadata struct {
+			Elements []struct {
+				VersionCode int `json:"versionCode"`
+			} `json:"elements"`
+		}
+
+		if err := json.Unmarshal(content, &metadata); err != nil {
+			return 0, fmt.Errorf("%s: %w", path, err)
+		}
+
+		code := 0
+		for _, e := range metadata.Elements {
+			if e.VersionCode > code {
+				code = e.VersionCode // the highest of the APK splits
+			}
+		}
+
+		if code == 0 {
+			return 0, fmt.Errorf("%s: no versionCode in elements", path)
+		}
+
+		return code, nil
+	}
+
+	code, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil || code <= 0 {
+		return 0, fmt.Errorf("%s: expected a positive version code", path)
+	}
+
+	return code, nil
+}